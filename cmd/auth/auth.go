@@ -0,0 +1,257 @@
+// Package auth checks the session token sent by the clients, for the HTTP
+// routes as well as the websocket. A token is accepted when its signature
+// and expiry are valid and its session still exists in the database.
+//
+// Bots authenticate with a long-lived API token instead, stored like the
+// other tokens as its SHA-256 in hex, and only get the scopes given to them.
+//
+// Sessions and bot tokens found in the database are cached for a short
+// while, one revoked on another backend is therefore refused after at most
+// AUTH_CACHE_TTL.
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrNoSession    = errors.New("session non existant")
+)
+
+// The scopes a bot can be given, each one opens a set of routes.
+const (
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+	ScopeServersJoin   = "servers:join"
+	ScopeGateway       = "gateway"
+)
+
+var BotScopes = []string{ScopeMessagesRead, ScopeMessagesWrite, ScopeServersJoin, ScopeGateway}
+
+// Identity is who a valid token belongs to. The session of a bot is its
+// token, BotSessionId of the token id, and never expires.
+type Identity struct {
+	UserId    string
+	SessionId string
+	ExpiresAt time.Time
+	Bot       bool
+	Scopes    []string
+}
+
+// Allows tells if the identity was given the scope, users have them all.
+func (identity Identity) Allows(scope string) bool {
+	if !identity.Bot {
+		return true
+	}
+
+	for _, allowed := range identity.Scopes {
+		if allowed == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func BotSessionId(tokenId string) string {
+	return "bot:" + tokenId
+}
+
+type cachedSession struct {
+	identity Identity
+	expires  time.Time
+}
+
+type Authenticator struct {
+	secret    []byte
+	cacheTTL  time.Duration
+	mu        sync.Mutex
+	sessions  map[string]cachedSession
+	lastSweep time.Time
+}
+
+func New(secret string, cacheTTL time.Duration) *Authenticator {
+	return &Authenticator{
+		secret:    []byte(secret),
+		cacheTTL:  cacheTTL,
+		sessions:  make(map[string]cachedSession),
+		lastSweep: time.Now(),
+	}
+}
+
+var Sessions *Authenticator
+
+// InitAuth signs and checks the tokens with SECRET, AUTH_CACHE_TTL (in
+// seconds, 10 by default) is how long a session is trusted without going
+// back to the database.
+func InitAuth() {
+	cacheTTL := 10 * time.Second
+	if value := env.Variable("AUTH_CACHE_TTL"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			log.Errorf("Invalid AUTH_CACHE_TTL=%q", value)
+		} else {
+			cacheTTL = time.Duration(seconds) * time.Second
+		}
+	}
+
+	Sessions = New(env.Variable("SECRET"), cacheTTL)
+}
+
+// Authenticate returns the identity of the token, ErrInvalidToken when it
+// can't be trusted and ErrNoSession when its session has been revoked.
+func (authenticator *Authenticator) Authenticate(tokenString string) (Identity, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return authenticator.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return Identity{}, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Identity{}, ErrInvalidToken
+	}
+
+	// The parser only checks the expiry when the claim is present.
+	expires, err := claims.GetExpirationTime()
+	if err != nil || expires == nil {
+		return Identity{}, ErrInvalidToken
+	}
+
+	identity := Identity{ExpiresAt: expires.Time}
+	identity.UserId, _ = claims["user_id"].(string)
+	identity.SessionId, _ = claims["session_id"].(string)
+	timezone, _ := claims["timezone"].(string)
+	userAgent, _ := claims["user_agent"].(string)
+	if identity.UserId == "" || identity.SessionId == "" {
+		return Identity{}, ErrInvalidToken
+	}
+
+	if cached, ok := authenticator.cached(identity.SessionId); ok && cached.UserId == identity.UserId {
+		return identity, nil
+	}
+
+	db := database.DB
+	var userId gocql.UUID
+	querySession := "SELECT user_id FROM sessions WHERE session_id = ? AND user_id = ? AND timezone = ? AND user_agent = ?"
+	if err := db.Query(querySession, identity.SessionId, identity.UserId, timezone, userAgent).Scan(&userId); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return Identity{}, ErrNoSession
+	}
+
+	var id gocql.UUID
+	queryUser := "SELECT id FROM users WHERE id = ?"
+	if err := db.Query(queryUser, userId).Scan(&id); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return Identity{}, ErrNoSession
+	}
+
+	authenticator.remember(identity.SessionId, identity)
+	return identity, nil
+}
+
+// AuthenticateBot returns the identity of the bot the API token belongs to,
+// ErrNoSession when it's unknown or has been revoked.
+func (authenticator *Authenticator) AuthenticateBot(token string) (Identity, error) {
+	if token == "" {
+		return Identity{}, ErrInvalidToken
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	tokenHash := hex.EncodeToString(sum[:])
+	if identity, ok := authenticator.cached(botCacheKey(tokenHash)); ok {
+		return identity, nil
+	}
+
+	db := database.DB
+	var botId gocql.UUID
+	var tokenId string
+	queryToken := "SELECT bot_id, token_id FROM bot_tokens WHERE token_hash = ?"
+	if err := db.Query(queryToken, tokenHash).Scan(&botId, &tokenId); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return Identity{}, ErrNoSession
+	}
+
+	identity := Identity{UserId: botId.String(), SessionId: BotSessionId(tokenId), Bot: true}
+	queryBot := "SELECT scopes FROM bots WHERE bot_id = ?"
+	if err := db.Query(queryBot, botId).Scan(&identity.Scopes); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return Identity{}, ErrNoSession
+	}
+
+	authenticator.remember(botCacheKey(tokenHash), identity)
+	return identity, nil
+}
+
+// Forget drops the session from the cache, to be called when it's revoked.
+func (authenticator *Authenticator) Forget(sessionId string) {
+	authenticator.mu.Lock()
+	defer authenticator.mu.Unlock()
+
+	delete(authenticator.sessions, sessionId)
+}
+
+// ForgetBotToken drops the bot token from the cache, to be called when it's
+// revoked or the scopes of its bot change.
+func (authenticator *Authenticator) ForgetBotToken(tokenHash string) {
+	authenticator.Forget(botCacheKey(tokenHash))
+}
+
+// Bot tokens share the cache with the sessions, the prefix keeps them apart.
+func botCacheKey(tokenHash string) string {
+	return "bot_token:" + tokenHash
+}
+
+func (authenticator *Authenticator) cached(key string) (Identity, bool) {
+	authenticator.mu.Lock()
+	defer authenticator.mu.Unlock()
+
+	session, ok := authenticator.sessions[key]
+	if !ok || !time.Now().Before(session.expires) {
+		return Identity{}, false
+	}
+
+	return session.identity, true
+}
+
+func (authenticator *Authenticator) remember(key string, identity Identity) {
+	if authenticator.cacheTTL <= 0 {
+		return
+	}
+
+	authenticator.mu.Lock()
+	defer authenticator.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(authenticator.lastSweep) >= time.Minute {
+		authenticator.lastSweep = now
+		for sessionId, session := range authenticator.sessions {
+			if now.After(session.expires) {
+				delete(authenticator.sessions, sessionId)
+			}
+		}
+	}
+
+	authenticator.sessions[key] = cachedSession{identity: identity, expires: now.Add(authenticator.cacheTTL)}
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	maxDisplayNameLength = 32
+	maxAboutLength       = 190
+)
+
+var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.]{2,32}$`)
+
+// UpdateAccount changes the profile and the credentials of the user, only
+// the fields sent are updated. Changing the email or the password needs the
+// current password, and the second factor when it's enabled.
+func UpdateAccount(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Username        *string `json:"username"`
+		DisplayName     *string `json:"displayName"`
+		About           *string `json:"about"`
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+		TwoFactorCode   string  `json:"two_factor_code"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the account's informations"})
+	}
+
+	user, err := GetUserById(c.Locals("user_id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	}
+	previous := user
+
+	if body.Username != nil && *body.Username != user.Username {
+		if !usernameRegex.MatchString(*body.Username) {
+			return c.Status(422).JSON(fiber.Map{"error": "A username is 2 to 32 letters, digits, dots or underscores"})
+		}
+		user.Username = *body.Username
+	}
+
+	if body.DisplayName != nil {
+		displayName := strings.TrimSpace(*body.DisplayName)
+		if len([]rune(displayName)) > maxDisplayNameLength {
+			return c.Status(422).JSON(fiber.Map{"error": fmt.Sprintf("The display name can't be longer than %d characters", maxDisplayNameLength)})
+		}
+		user.DisplayName = displayName
+	}
+
+	if body.About != nil {
+		if len([]rune(*body.About)) > maxAboutLength {
+			return c.Status(422).JSON(fiber.Map{"error": fmt.Sprintf("The about can't be longer than %d characters", maxAboutLength)})
+		}
+		user.About = *body.About
+	}
+
+	if body.Email != nil {
+		addr, err := mail.ParseAddress(*body.Email)
+		if err != nil {
+			return c.Status(422).JSON(fiber.Map{"error": "Invalid email address"})
+		}
+
+		if addr.Address != user.Email {
+			user.Email = addr.Address
+			user.EmailVerified = false
+		}
+	}
+
+	passwordChanged := body.Password != nil
+	if passwordChanged && len(*body.Password) < minPasswordLength {
+		return c.Status(422).JSON(fiber.Map{"error": fmt.Sprintf("The password must be at least %d characters long", minPasswordLength)})
+	}
+
+	emailChanged := user.Email != previous.Email
+	if emailChanged || passwordChanged {
+		if status, err := checkCurrentPassword(previous, body.CurrentPassword); err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if status, err := checkSecondFactor(user.Id, body.TwoFactorCode); err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if passwordChanged {
+		hash, err := hashPassword(*body.Password)
+		if err != nil {
+			log.Error(err)
+			return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the account"})
+		}
+		user.Password = hash
+	}
+
+	// The new username and email are claimed first, so two accounts can't
+	// end up with the same one, and given back if the update fails.
+	var claimed [][2]string
+	release := func() {
+		for _, claim := range claimed {
+			releaseUserLookup(claim[0], claim[1], user.Id)
+		}
+	}
+
+	if user.Username != previous.Username {
+		if ok, err := claimUserLookup("existing_username", user.Username, user.Id); err != nil {
+			log.Error(err)
+			return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the account"})
+		} else if !ok {
+			return c.Status(409).JSON(fiber.Map{"error": "This username is already taken"})
+		}
+		claimed = append(claimed, [2]string{"existing_username", user.Username})
+	}
+
+	if emailChanged {
+		if ok, err := claimUserLookup("existing_email", user.Email, user.Id); err != nil {
+			release()
+			log.Error(err)
+			return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the account"})
+		} else if !ok {
+			release()
+			return c.Status(409).JSON(fiber.Map{"error": "This email address is already used"})
+		}
+		claimed = append(claimed, [2]string{"existing_email", user.Email})
+	}
+
+	queryUpdateUser := "UPDATE users SET username = ?, displayname = ?, about = ?, email = ?, email_verified = ?, password = ? WHERE id = ?"
+	if err := db.Query(queryUpdateUser, user.Username, user.DisplayName, user.About, user.Email, user.EmailVerified, user.Password, user.Id).Exec(); err != nil {
+		release()
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the account"})
+	}
+
+	if user.Username != previous.Username {
+		releaseUserLookup("existing_username", previous.Username, user.Id)
+	}
+
+	if emailChanged {
+		releaseUserLookup("existing_email", previous.Email, user.Id)
+		if err := sendVerificationEmail(user); err != nil {
+			log.Errorf("Error when sending the verification email: %v", err)
+		}
+	}
+
+	if passwordChanged {
+		currentSessionId, _ := c.Locals("session_id").(string)
+		if _, err := revokeAllSessions(user.Id, currentSessionId); err != nil {
+			log.Error(err)
+		}
+	}
+
+	if user.Username != previous.Username || user.DisplayName != previous.DisplayName || user.About != previous.About || emailChanged {
+		broadcastUserUpdate(user)
+	}
+
+	return c.JSON(user)
+}
+
+// checkCurrentPassword confirms it's the user before a sensitive change. An
+// account created through a provider has no password, the user logs in
+// again through the provider instead (OIDCAuthorize with reauth=true).
+func checkCurrentPassword(user models.User, password string) (int, error) {
+	db := database.DB
+
+	if user.Password != "" {
+		if !checkHashedPassword(user.Password, password) {
+			return 401, errors.New("Your password is invalid")
+		}
+		return 200, nil
+	}
+
+	var reauthenticatedAt time.Time
+	queryReauth := "SELECT reauthenticated_at FROM recent_reauths WHERE user_id = ?"
+	if err := db.Query(queryReauth, user.Id).Scan(&reauthenticatedAt); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+			return 500, errors.New("Couldn't check your identity")
+		}
+		return 401, errors.New("Your account has no password, log in again with your provider to confirm it's you")
+	}
+
+	return 200, nil
+}
+
+// claimUserLookup reserves the username or the email, table being
+// existing_username or existing_email.
+func claimUserLookup(table string, value string, userId gocql.UUID) (bool, error) {
+	db := database.DB
+
+	queryClaim := fmt.Sprintf("INSERT INTO %s (%s, user_id) VALUES (?, ?) IF NOT EXISTS", table, userLookupColumn(table))
+	return db.Query(queryClaim, value, userId).MapScanCAS(map[string]interface{}{})
+}
+
+// releaseUserLookup frees the username or the email, if it's still the
+// user's.
+func releaseUserLookup(table string, value string, userId gocql.UUID) {
+	db := database.DB
+
+	queryRelease := fmt.Sprintf("DELETE FROM %s WHERE %s = ? IF user_id = ?", table, userLookupColumn(table))
+	if _, err := db.Query(queryRelease, value, userId).MapScanCAS(map[string]interface{}{}); err != nil {
+		log.Error(err)
+	}
+}
+
+func userLookupColumn(table string) string {
+	if table == "existing_email" {
+		return "email"
+	}
+
+	return "username"
+}
+
+// broadcastUserUpdate sends the new profile to the other connections of the
+// user and to everyone sharing a server with them, the email only to the
+// user.
+func broadcastUserUpdate(user models.User) {
+	db := database.DB
+
+	profile := &protobuf.User{
+		Id:          user.Id.String(),
+		Username:    user.Username,
+		About:       user.About,
+		Avatar:      user.Avatar,
+		Banner:      user.Banner,
+		DisplayName: user.DisplayName,
+	}
+
+	data, err := proto.Marshal(&protobuf.ServerMessage{
+		Type:    "user_update",
+		Payload: &protobuf.ServerMessage_UserUpdate{UserUpdate: profile},
+	})
+	if err != nil {
+		fmt.Println("Error when transforming the message into protobuf", err)
+		return
+	}
+
+	profile.Email = user.Email
+	ownData, err := proto.Marshal(&protobuf.ServerMessage{
+		Type:    "user_update",
+		Payload: &protobuf.ServerMessage_UserUpdate{UserUpdate: profile},
+	})
+	if err != nil {
+		fmt.Println("Error when transforming the message into protobuf", err)
+		return
+	}
+	sendToUser(user.Id, ownData, nil)
+
+	var serversId []string
+	queryGetServers := "SELECT servers FROM user_to_servers WHERE user_id = ?"
+	if err := db.Query(queryGetServers, user.Id).Scan(&serversId); err != nil && err != gocql.ErrNotFound {
+		log.Error(err)
+	}
+
+	sent := map[gocql.UUID]bool{user.Id: true}
+	for _, serverId := range serversId {
+		for _, member := range getServerMembers(serverId) {
+			if !sent[member] {
+				sent[member] = true
+				sendToUser(member, data, nil)
+			}
+		}
+	}
+}
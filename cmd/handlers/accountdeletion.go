@@ -0,0 +1,548 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/storage"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// An account is deleted by a background job going through the steps below
+// in order. The step reached is saved in account_deletions after each one,
+// so a job interrupted by a restart resumes where it stopped, every step can
+// safely run twice. The job is removed once the account is gone.
+const (
+	deletionStepLookups = iota
+	deletionStepSessions
+	deletionStepMessages
+	deletionStepDMs
+	deletionStepServers
+	deletionStepFriends
+	deletionStepScheduled
+	deletionStepMedia
+	deletionStepBots
+	deletionStepUser
+	deletionStepDone
+
+	ownedServersTransfer = "transfer"
+	ownedServersDelete   = "delete"
+
+	// A job is leased by the backend running it, another one only picks it
+	// up once the lease expired.
+	deletionLease        = 10 * time.Minute
+	deletionPollInterval = time.Minute
+)
+
+// deletedUserId is the author the messages of deleted accounts are given to.
+var deletedUserId = gocql.UUID{}
+
+// deletedUser stands for the author of a message whose account is gone.
+func deletedUser() models.User {
+	return models.User{Id: deletedUserId, Username: "deleted_user", DisplayName: "Deleted User"}
+}
+
+// DeleteAccount checks the password, or a recent login through a provider
+// for an account without one, and the second factor again, logs out
+// every session and starts the deletion job. Owned servers are handed to
+// another member ("transfer", the default) or deleted ("delete").
+func DeleteAccount(c *fiber.Ctx) error {
+	type BodyRequest struct {
+		Password      string `json:"password"`
+		TwoFactorCode string `json:"two_factor_code"`
+		OwnedServers  string `json:"owned_servers"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the request"})
+	}
+
+	if body.OwnedServers == "" {
+		body.OwnedServers = ownedServersTransfer
+	}
+
+	if body.OwnedServers != ownedServersTransfer && body.OwnedServers != ownedServersDelete {
+		return c.Status(422).JSON(fiber.Map{"error": "owned_servers must be transfer or delete"})
+	}
+
+	user, err := GetUserById(c.Locals("user_id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if status, err := checkCurrentPassword(user, body.Password); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if status, err := checkSecondFactor(user.Id, body.TwoFactorCode); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	applied, err := createAccountDeletion(user, body.OwnedServers)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't delete the account"})
+	}
+
+	if !applied {
+		return c.Status(409).JSON(fiber.Map{"error": "The account is already being deleted"})
+	}
+
+	if _, err := revokeAllSessions(user.Id, ""); err != nil {
+		log.Error(err)
+	}
+	clearSessionCookies(c)
+
+	go runAccountDeletion(user.Id)
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// createAccountDeletion saves the job deleting the account, false when
+// there's already one.
+func createAccountDeletion(user models.User, ownedServers string) (bool, error) {
+	db := database.DB
+
+	now := time.Now()
+	queryCreateJob := "INSERT INTO account_deletions (user_id, username, email, avatar, banner, owned_servers, step, requested_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) IF NOT EXISTS"
+	return db.Query(queryCreateJob, user.Id, user.Username, user.Email, user.Avatar, user.Banner, ownedServers, deletionStepLookups, now, now).MapScanCAS(map[string]interface{}{})
+}
+
+// StartAccountDeletions resumes the jobs left unfinished, by a restart or a
+// backend that went away.
+func StartAccountDeletions() {
+	go func() {
+		ticker := time.NewTicker(deletionPollInterval)
+		defer ticker.Stop()
+
+		resumeAccountDeletions()
+		for range ticker.C {
+			resumeAccountDeletions()
+		}
+	}()
+}
+
+func resumeAccountDeletions() {
+	db := database.DB
+
+	var userIds []gocql.UUID
+	queryJobs := "SELECT user_id, step FROM account_deletions"
+	scanner := db.Query(queryJobs).Iter().Scanner()
+	for scanner.Next() {
+		var userId gocql.UUID
+		var step int
+		if err := scanner.Scan(&userId, &step); err != nil {
+			log.Error(err)
+			continue
+		}
+
+		if step < deletionStepDone {
+			userIds = append(userIds, userId)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+		return
+	}
+
+	for _, userId := range userIds {
+		runAccountDeletion(userId)
+	}
+}
+
+type accountDeletion struct {
+	UserId       gocql.UUID
+	Username     string
+	Email        string
+	Avatar       string
+	Banner       string
+	OwnedServers string
+	Step         int
+	LeaseUntil   time.Time
+}
+
+func runAccountDeletion(userId gocql.UUID) {
+	db := database.DB
+
+	var job accountDeletion
+	queryJob := "SELECT user_id, username, email, avatar, banner, owned_servers, step, lease_until FROM account_deletions WHERE user_id = ?"
+	if err := db.Query(queryJob, userId).Scan(&job.UserId, &job.Username, &job.Email, &job.Avatar, &job.Banner, &job.OwnedServers, &job.Step, &job.LeaseUntil); err != nil {
+		log.Error(err)
+		return
+	}
+
+	if job.Step >= deletionStepDone || !leaseAccountDeletion(job) {
+		return
+	}
+
+	for job.Step < deletionStepDone {
+		if err := runDeletionStep(job); err != nil {
+			log.Errorf("Error when deleting the account %s at step %d, it will be retried: %v", job.UserId, job.Step, err)
+			return
+		}
+
+		job.Step++
+		if job.Step == deletionStepDone {
+			break
+		}
+
+		job.LeaseUntil = time.Now().Add(deletionLease)
+		queryStep := "UPDATE account_deletions SET step = ?, lease_until = ?, updated_at = ? WHERE user_id = ?"
+		if err := db.Query(queryStep, job.Step, job.LeaseUntil, time.Now(), job.UserId).Exec(); err != nil {
+			log.Error(err)
+			return
+		}
+	}
+
+	// The job holds the username and the email, it goes away with the rest.
+	if err := db.Query("DELETE FROM account_deletions WHERE user_id = ?", job.UserId).Exec(); err != nil {
+		log.Error(err)
+		return
+	}
+
+	log.Infof("Account %s deleted", job.UserId)
+}
+
+// leaseAccountDeletion claims the job, unless another backend holds it.
+func leaseAccountDeletion(job accountDeletion) bool {
+	db := database.DB
+	now := time.Now()
+
+	if job.LeaseUntil.After(now) {
+		return false
+	}
+
+	var previous interface{}
+	if !job.LeaseUntil.IsZero() {
+		previous = job.LeaseUntil
+	}
+
+	queryLease := "UPDATE account_deletions SET lease_until = ? WHERE user_id = ? IF lease_until = ?"
+	applied, err := db.Query(queryLease, now.Add(deletionLease), job.UserId, previous).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+
+	return applied
+}
+
+func runDeletionStep(job accountDeletion) error {
+	switch job.Step {
+	case deletionStepLookups:
+		releaseUserLookup("existing_username", job.Username, job.UserId)
+		releaseUserLookup("existing_email", job.Email, job.UserId)
+		return deleteAccountIdentities(job.UserId)
+	case deletionStepSessions:
+		return deleteAccountSessions(job.UserId)
+	case deletionStepMessages:
+		return anonymizeAccountMessages(job.UserId)
+	case deletionStepDMs:
+		return leaveAccountDMs(job.UserId)
+	case deletionStepServers:
+		return leaveAccountServers(job.UserId, job.OwnedServers)
+	case deletionStepFriends:
+		return deleteAccountFriends(job.UserId)
+	case deletionStepScheduled:
+		return database.DB.Query("DELETE FROM scheduled_items WHERE user_id = ?", job.UserId).Exec()
+	case deletionStepMedia:
+		return deleteAccountMedia(job)
+	case deletionStepBots:
+		return deleteAccountBots(job.UserId)
+	case deletionStepUser:
+		return deleteAccountUser(job.UserId)
+	}
+
+	return nil
+}
+
+func deleteAccountSessions(userId gocql.UUID) error {
+	db := database.DB
+
+	if _, err := revokeAllSessions(userId, ""); err != nil {
+		return err
+	}
+
+	return db.Query("DELETE FROM user_two_factor WHERE user_id = ?", userId).Exec()
+}
+
+// deleteAccountIdentities unlinks the providers the user logged in with.
+func deleteAccountIdentities(userId gocql.UUID) error {
+	db := database.DB
+
+	var provider, subject string
+	iter := db.Query("SELECT provider, subject FROM user_to_identities WHERE user_id = ?", userId).Iter()
+	for iter.Scan(&provider, &subject) {
+		if err := db.Query("DELETE FROM user_identities WHERE provider = ? AND subject = ?", provider, subject).Exec(); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	return db.Query("DELETE FROM user_to_identities WHERE user_id = ?", userId).Exec()
+}
+
+// anonymizeAccountMessages gives the messages of the user, in the servers and
+// the DMs, even the ones they left, to the deleted user. Their content stays for the conversations to
+// make sense.
+func anonymizeAccountMessages(userId gocql.UUID) error {
+	db := database.DB
+
+	ghost := deletedUser()
+	queryGhost := "INSERT INTO users (id, username, displayname) VALUES (?, ?, ?) IF NOT EXISTS"
+	if _, err := db.Query(queryGhost, ghost.Id, ghost.Username, ghost.DisplayName).MapScanCAS(map[string]interface{}{}); err != nil {
+		return err
+	}
+
+	channels, err := getAuthoredChannels(userId)
+	if err != nil {
+		return err
+	}
+
+	queryMessages := "SELECT created_at, message_id FROM messages WHERE channel_id = ? AND sender_id = ? ALLOW FILTERING"
+	queryAnonymize := "UPDATE messages SET sender_id = ? WHERE channel_id = ? AND created_at = ? AND message_id = ?"
+	for _, channelId := range channels {
+		var createdAt time.Time
+		var messageId gocql.UUID
+
+		iter := db.Query(queryMessages, channelId, userId).Iter()
+		for iter.Scan(&createdAt, &messageId) {
+			if err := db.Query(queryAnonymize, deletedUserId, channelId, createdAt, messageId).Exec(); err != nil {
+				iter.Close()
+				return err
+			}
+		}
+
+		if err := iter.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getAuthoredChannels lists the channels the user may have written in: the
+// ones they posted in, recorded by storeMessage, and the ones they're still
+// in for the messages written before that was recorded.
+func getAuthoredChannels(userId gocql.UUID) ([]string, error) {
+	db := database.DB
+
+	channels, err := getAccountChannels(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, channelId := range channels {
+		seen[channelId] = true
+	}
+
+	var channelId string
+	iter := db.Query("SELECT channel_id FROM user_to_message_channels WHERE user_id = ?", userId).Iter()
+	for iter.Scan(&channelId) {
+		if !seen[channelId] {
+			seen[channelId] = true
+			channels = append(channels, channelId)
+		}
+	}
+
+	return channels, iter.Close()
+}
+
+// getAccountChannels lists the channels of the servers and the DMs of the
+// user.
+func getAccountChannels(userId gocql.UUID) ([]string, error) {
+	db := database.DB
+	var channels []string
+
+	var serversId []string
+	queryGetServers := "SELECT servers FROM user_to_servers WHERE user_id = ?"
+	if err := db.Query(queryGetServers, userId).Scan(&serversId); err != nil && err != gocql.ErrNotFound {
+		return nil, err
+	}
+
+	for _, serverId := range serversId {
+		channels = append(channels, utils.GetChannelsIDFromServerUsingProps(serverId)...)
+	}
+
+	var channelId string
+	iter := db.Query("SELECT channel_id FROM user_to_dms WHERE user_id = ?", userId).Iter()
+	for iter.Scan(&channelId) {
+		channels = append(channels, channelId)
+	}
+
+	return channels, iter.Close()
+}
+
+// leaveAccountDMs leaves the groups, the one-to-one DMs stay in the list of
+// the partner.
+func leaveAccountDMs(userId gocql.UUID) error {
+	db := database.DB
+
+	var dms []models.DirectMessage
+	var dm models.DirectMessage
+	iter := db.Query("SELECT channel_id, type FROM user_to_dms WHERE user_id = ?", userId).Iter()
+	for iter.Scan(&dm.ChannelId, &dm.Type) {
+		dms = append(dms, dm)
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	for _, dm := range dms {
+		if dm.Type == dmTypeGroup {
+			group, err := getGroupDM(dm.ChannelId)
+			if err != nil {
+				log.Error(err)
+			}
+			if err := leaveGroupDM(dm.ChannelId, group, userId); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := removeGroupDMParticipant(db, dm.ChannelId, userId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// leaveAccountServers leaves the servers of the user. The owned ones are
+// handed to another member, or deleted when asked to or when the user was
+// alone in it.
+func leaveAccountServers(userId gocql.UUID, ownedServers string) error {
+	db := database.DB
+
+	var serversId []string
+	queryGetServers := "SELECT servers FROM user_to_servers WHERE user_id = ?"
+	if err := db.Query(queryGetServers, userId).Scan(&serversId); err != nil && err != gocql.ErrNotFound {
+		return err
+	}
+
+	for _, serverId := range serversId {
+		server, err := utils.GetServerInformations(serverId)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		if server.Owner == userId {
+			var heir gocql.UUID
+			for _, member := range getServerMembers(serverId) {
+				if member != userId {
+					heir = member
+					break
+				}
+			}
+
+			if ownedServers == ownedServersDelete || heir == (gocql.UUID{}) {
+				if _, err := deleteServer(serverId); err != nil {
+					return err
+				}
+				continue
+			}
+
+			before := server
+			server.Owner = heir
+			queryTransferOwnership := "UPDATE servers SET owner = ? WHERE server_id = ?"
+			if err := db.Query(queryTransferOwnership, heir, serverId).Exec(); err != nil {
+				return err
+			}
+
+			recordAudit(serverId, userId.String(), auditActionServerUpdate, "server", serverId, before, server)
+			broadcastServerChanges(getServerMembers(serverId), Options{Server: &server}, "server_update")
+		}
+
+		if _, err := leaveServer(userId.String(), serverId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteAccountFriends(userId gocql.UUID) error {
+	db := database.DB
+
+	var friends []gocql.UUID
+	var friendId gocql.UUID
+	iter := db.Query("SELECT friend_id FROM friends WHERE user_id = ?", userId).Iter()
+	for iter.Scan(&friendId) {
+		friends = append(friends, friendId)
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	queryDeleteFriend := "DELETE FROM friends WHERE user_id = ? AND friend_id = ?"
+	for _, friendId := range friends {
+		if err := db.Query(queryDeleteFriend, friendId, userId).Exec(); err != nil {
+			return err
+		}
+	}
+
+	return db.Query("DELETE FROM friends WHERE user_id = ?", userId).Exec()
+}
+
+// deleteAccountMedia deletes the avatar and the banner, their keys are found
+// back from the URLs saved when the job started, and every data export of
+// the user.
+func deleteAccountMedia(job accountDeletion) error {
+	exports, err := storage.Store.List(fmt.Sprintf("%s%s/", exportPrefix, job.UserId))
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if err := storage.Store.Delete(export.Key); err != nil {
+			return err
+		}
+	}
+
+	for media, url := range map[string]string{"avatar": job.Avatar, "banner": job.Banner} {
+		index := strings.Index(url, fmt.Sprintf("user_%s/", media))
+		if index < 0 {
+			continue
+		}
+
+		if err := deleteMedia(url[index:], mediaSizes[media]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteAccountUser(userId gocql.UUID) error {
+	db := database.DB
+
+	for _, query := range []string{
+		"DELETE FROM user_to_servers WHERE user_id = ?",
+		"DELETE FROM user_sessions WHERE user_id = ?",
+		"DELETE FROM user_to_message_channels WHERE user_id = ?",
+		"DELETE FROM data_exports WHERE user_id = ?",
+		"DELETE FROM users WHERE id = ?",
+	} {
+		if err := db.Query(query, userId).Exec(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
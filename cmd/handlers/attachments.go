@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/storage"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	maxAttachmentSize       = 25 << 20
+	maxAttachmentsByMessage = 10
+
+	attachmentStatusPending  = "pending"
+	attachmentStatusUploaded = "uploaded"
+	attachmentStatusLinked   = "linked"
+)
+
+// NewAttachment registers a file the user is about to upload in a channel
+// and returns the presigned URL to PUT it to.
+func NewAttachment(c *fiber.Ctx) error {
+	db := database.DB
+	channelId := c.Params("channelId")
+	type BodyRequest struct {
+		Filename    string `json:"filename"`
+		ContentType string `json:"content_type"`
+		Size        int64  `json:"size"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the attachment"})
+	}
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the attachment"})
+	}
+
+	if !containsUUID(getAllUsersFromChannel(channelId, db), userId) {
+		return c.Status(403).JSON(fiber.Map{"error": "You can't upload files in this channel"})
+	}
+
+	filename := sanitizeFilename(body.Filename)
+	if filename == "" {
+		return c.Status(422).JSON(fiber.Map{"error": "Invalid filename"})
+	}
+
+	if body.Size <= 0 || body.Size > maxAttachmentSize {
+		return c.Status(413).JSON(fiber.Map{"error": "This file is too large"})
+	}
+
+	attachment := models.Attachment{
+		Id:          gocql.MustRandomUUID(),
+		ChannelId:   channelId,
+		UploaderId:  userId,
+		Filename:    filename,
+		Size:        body.Size,
+		ContentType: body.ContentType,
+		Status:      attachmentStatusPending,
+	}
+	attachment.ObjectKey = fmt.Sprintf("attachments/%s/%s/%s", channelId, attachment.Id, filename)
+	attachment.Url = storage.Store.URL(attachment.ObjectKey)
+
+	queryCreateAttachment := "INSERT INTO attachments (attachment_id, channel_id, uploader_id, filename, size, content_type, object_key, url, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if err := db.Query(queryCreateAttachment, attachment.Id, attachment.ChannelId, attachment.UploaderId, attachment.Filename, attachment.Size, attachment.ContentType, attachment.ObjectKey, attachment.Url, attachment.Status, time.Now()).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the attachment"})
+	}
+
+	url, err := presignPut(attachment.ObjectKey)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the presigned URL"})
+	}
+
+	return c.Status(200).JSON(fiber.Map{"attachment": attachment, "url": url})
+}
+
+// CompleteAttachment is called by the client once the upload went through,
+// the object is checked in the bucket before the attachment can be sent.
+func CompleteAttachment(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the attachment"})
+	}
+
+	attachmentId, err := gocql.ParseUUID(c.Params("attachmentId"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Attachment not found"})
+	}
+
+	attachment, err := getAttachment(attachmentId)
+	if err != nil || attachment.UploaderId.String() != c.Locals("user_id").(string) {
+		return c.Status(404).JSON(fiber.Map{"error": "Attachment not found"})
+	}
+
+	if attachment.Status != attachmentStatusPending {
+		return c.JSON(attachment)
+	}
+
+	head, err := storage.Store.Head(attachment.ObjectKey)
+	if err != nil {
+		log.Errorf("Error when checking the uploaded attachment: %v", err)
+		return c.Status(404).JSON(fiber.Map{"error": "The file hasn't been uploaded"})
+	}
+
+	if head.Size > maxAttachmentSize {
+		if err := storage.Store.Delete(attachment.ObjectKey); err != nil {
+			log.Error(err)
+		}
+		return c.Status(413).JSON(fiber.Map{"error": "This file is too large"})
+	}
+
+	attachment.Size = head.Size
+	if head.ContentType != "" {
+		attachment.ContentType = head.ContentType
+	}
+	attachment.Width = body.Width
+	attachment.Height = body.Height
+	attachment.Status = attachmentStatusUploaded
+
+	queryCompleteAttachment := "UPDATE attachments SET size = ?, content_type = ?, width = ?, height = ?, status = ? WHERE attachment_id = ?"
+	if err := db.Query(queryCompleteAttachment, attachment.Size, attachment.ContentType, attachment.Width, attachment.Height, attachment.Status, attachment.Id).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't complete the attachment"})
+	}
+
+	return c.JSON(attachment)
+}
+
+// linkAttachments attaches the uploaded files the message refers to, they
+// must have been uploaded by the sender, who is the authenticated user, in
+// the same channel and not be used by another message already. Each one is
+// linked with a LWT so two messages sent at once can't both take it.
+func linkAttachments(db *gocql.Session, message *models.Message) error {
+	if len(message.AttachmentIds) > maxAttachmentsByMessage {
+		return fmt.Errorf("Too many attachments")
+	}
+
+	message.Attachments = nil
+	for _, attachmentId := range message.AttachmentIds {
+		attachment, err := getAttachment(attachmentId)
+		if err != nil {
+			return fmt.Errorf("Attachment not found")
+		}
+
+		if attachment.UploaderId != message.User.Id || attachment.ChannelId != message.ChannelId || attachment.Status != attachmentStatusUploaded {
+			return fmt.Errorf("Invalid attachment")
+		}
+
+		message.Attachments = append(message.Attachments, attachment)
+	}
+
+	queryLinkAttachment := "UPDATE attachments SET status = ?, message_id = ? WHERE attachment_id = ? IF message_id = null"
+	for i, attachment := range message.Attachments {
+		applied, err := db.Query(queryLinkAttachment, attachmentStatusLinked, message.MessageId, attachment.Id).MapScanCAS(map[string]interface{}{})
+		if err == nil && !applied {
+			err = fmt.Errorf("Attachment already used")
+		}
+
+		if err != nil {
+			unlinkAttachments(db, message.MessageId, message.Attachments[:i])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unlinkAttachments gives back the attachments linked to a message which
+// couldn't be sent.
+func unlinkAttachments(db *gocql.Session, messageId gocql.UUID, attachments []models.Attachment) {
+	queryUnlinkAttachment := "UPDATE attachments SET status = ?, message_id = null WHERE attachment_id = ? IF message_id = ?"
+	for _, attachment := range attachments {
+		if _, err := db.Query(queryUnlinkAttachment, attachmentStatusUploaded, attachment.Id, messageId).MapScanCAS(map[string]interface{}{}); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+func getAttachment(attachmentId gocql.UUID) (models.Attachment, error) {
+	db := database.DB
+	var attachment models.Attachment
+
+	queryGetAttachment := "SELECT attachment_id, channel_id, uploader_id, filename, size, content_type, width, height, object_key, url, status, message_id FROM attachments WHERE attachment_id = ?"
+	if err := db.Query(queryGetAttachment, attachmentId).Scan(&attachment.Id, &attachment.ChannelId, &attachment.UploaderId, &attachment.Filename, &attachment.Size, &attachment.ContentType, &attachment.Width, &attachment.Height, &attachment.ObjectKey, &attachment.Url, &attachment.Status, &attachment.MessageId); err != nil {
+		return attachment, err
+	}
+
+	return attachment, nil
+}
+
+func getMessageAttachments(attachmentIds []gocql.UUID) []models.Attachment {
+	attachments := []models.Attachment{}
+	for _, attachmentId := range attachmentIds {
+		attachment, err := getAttachment(attachmentId)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments
+}
+
+func sanitizeFilename(filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	filename = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == '?' || r == '#' || r == '%' {
+			return '_'
+		}
+		return r
+	}, filename)
+
+	if filename == "." || filename == "/" || len(filename) > 255 {
+		return ""
+	}
+
+	return filename
+}
+
+func attachmentToProtobuf(attachment models.Attachment) *protobuf.Attachment {
+	return &protobuf.Attachment{
+		Id:          attachment.Id.String(),
+		Filename:    attachment.Filename,
+		Size:        attachment.Size,
+		ContentType: attachment.ContentType,
+		Width:       int32(attachment.Width),
+		Height:      int32(attachment.Height),
+		Url:         attachment.Url,
+	}
+}
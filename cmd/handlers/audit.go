@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	auditActionChannelCreate = "channel_create"
+	auditActionChannelDelete = "channel_delete"
+	auditActionServerUpdate  = "server_update"
+	auditActionMessagePin    = "message_pin"
+	auditActionMessageUnpin  = "message_unpin"
+	auditActionMemberJoin    = "member_join"
+	auditActionMemberLeave   = "member_leave"
+	defaultAuditLogsByPage   = 50
+	maxAuditLogsByPage       = 100
+)
+
+// GetAuditLogs lists the audit log of a server, most recent first as the
+// entries are clustered by descending timeuuid. It's paginated with
+// ?before=<entry id> and can be filtered by ?action= and ?actor=.
+func GetAuditLogs(c *fiber.Ctx) error {
+	db := database.DB
+	serverId := c.Params("serverId")
+
+	server, err := utils.GetServerInformations(serverId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Server not found"})
+	}
+
+	if !canManageServer(server, c.Locals("user_id").(string)) {
+		return c.Status(403).JSON(fiber.Map{"error": "You can't see the audit log of this server"})
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultAuditLogsByPage)))
+	if err != nil || limit <= 0 || limit > maxAuditLogsByPage {
+		limit = defaultAuditLogsByPage
+	}
+
+	query := "SELECT entry_id, actor_id, action, target_type, target_id, changes FROM audit_logs WHERE server_id = ?"
+	args := []interface{}{serverId}
+	if before := c.Query("before"); before != "" {
+		beforeId, err := gocql.ParseUUID(before)
+		if err != nil {
+			return c.Status(422).JSON(fiber.Map{"error": "Invalid cursor"})
+		}
+		query += " AND entry_id < ?"
+		args = append(args, beforeId)
+	}
+
+	if action := c.Query("action"); action != "" {
+		query += " AND action = ?"
+		args = append(args, action)
+	}
+
+	if actor := c.Query("actor"); actor != "" {
+		actorId, err := gocql.ParseUUID(actor)
+		if err != nil {
+			return c.Status(422).JSON(fiber.Map{"error": "Invalid actor"})
+		}
+		query += " AND actor_id = ?"
+		args = append(args, actorId)
+	}
+
+	query += " LIMIT ? ALLOW FILTERING"
+	args = append(args, limit)
+
+	entries := []models.AuditLogEntry{}
+	scanner := db.Query(query, args...).Iter().Scanner()
+	for scanner.Next() {
+		entry := models.AuditLogEntry{ServerId: serverId}
+		var changes string
+		if err := scanner.Scan(&entry.Id, &entry.ActorId, &entry.Action, &entry.TargetType, &entry.TargetId, &changes); err != nil {
+			log.Error(err)
+			continue
+		}
+
+		if changes != "" {
+			if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+				log.Error(err)
+			}
+		}
+		entry.CreatedAt = entry.Id.Time()
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the audit log"})
+	}
+
+	response := fiber.Map{"entries": entries}
+	if len(entries) == limit {
+		response["next"] = entries[len(entries)-1].Id
+	}
+
+	return c.JSON(response)
+}
+
+// recordAudit adds an entry to the audit log of the server, with the fields
+// that differ between before and after. Either of them can be nil when the
+// target was created or deleted. Failing to write it never fails the action.
+func recordAudit(serverId string, actorId string, action string, targetType string, targetId string, before interface{}, after interface{}) {
+	db := database.DB
+
+	actor, err := gocql.ParseUUID(actorId)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	changes, err := json.Marshal(auditDiff(before, after))
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	queryCreateEntry := "INSERT INTO audit_logs (server_id, entry_id, actor_id, action, target_type, target_id, changes) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	if err := db.Query(queryCreateEntry, serverId, gocql.UUIDFromTime(time.Now()), actor, action, targetType, targetId, string(changes)).Exec(); err != nil {
+		log.Errorf("Error when writing the audit log: %v", err)
+	}
+}
+
+func auditDiff(before interface{}, after interface{}) map[string]models.AuditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+	changes := make(map[string]models.AuditChange)
+
+	for key, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[key]) {
+			changes[key] = models.AuditChange{Before: value, After: afterFields[key]}
+		}
+	}
+
+	for key, value := range afterFields {
+		if _, ok := beforeFields[key]; !ok && value != nil {
+			changes[key] = models.AuditChange{After: value}
+		}
+	}
+
+	return changes
+}
+
+// auditFields flattens a value the way it's shown to the clients, through
+// its JSON representation.
+func auditFields(value interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if value == nil {
+		return fields
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Error(err)
+		return fields
+	}
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		log.Error(err)
+	}
+
+	return fields
+}
@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/auth"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	maxBotsPerUser  = 10
+	maxTokensPerBot = 5
+	maxTokenName    = 64
+)
+
+var errBotMissingGatewayScope = errors.New("the bot is missing the gateway scope")
+
+// CreateBot creates a bot account owned by the user. A bot has no password
+// nor email, it's used with the API tokens created for it.
+func CreateBot(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Username    string   `json:"username"`
+		DisplayName string   `json:"displayName"`
+		Scopes      []string `json:"scopes"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the bot's informations"})
+	}
+
+	ownerId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the bot"})
+	}
+
+	if !usernameRegex.MatchString(body.Username) {
+		return c.Status(422).JSON(fiber.Map{"error": "A username is 2 to 32 letters, digits, dots or underscores"})
+	}
+
+	displayName := strings.TrimSpace(body.DisplayName)
+	if len([]rune(displayName)) > maxDisplayNameLength {
+		return c.Status(422).JSON(fiber.Map{"error": fmt.Sprintf("The display name can't be longer than %d characters", maxDisplayNameLength)})
+	}
+
+	scopes, err := validateBotScopes(body.Scopes)
+	if err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	bots, err := getUserBots(ownerId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the bot"})
+	}
+
+	if len(bots) >= maxBotsPerUser {
+		return c.Status(403).JSON(fiber.Map{"error": fmt.Sprintf("You can't have more than %d bots", maxBotsPerUser)})
+	}
+
+	bot := models.Bot{
+		Id:          gocql.MustRandomUUID(),
+		OwnerId:     ownerId,
+		Username:    body.Username,
+		DisplayName: displayName,
+		Scopes:      scopes,
+		CreatedAt:   time.Now(),
+	}
+
+	if ok, err := claimUserLookup("existing_username", bot.Username, bot.Id); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the bot"})
+	} else if !ok {
+		return c.Status(409).JSON(fiber.Map{"error": "This username is already taken"})
+	}
+
+	queryCreateUser := "INSERT INTO users (id, username, displayname, email, email_verified, password, bot) VALUES (?, ?, ?, '', false, '', true)"
+	if err := db.Query(queryCreateUser, bot.Id, bot.Username, bot.DisplayName).Exec(); err != nil {
+		releaseUserLookup("existing_username", bot.Username, bot.Id)
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the bot"})
+	}
+
+	queryCreateBot := "INSERT INTO bots (bot_id, owner_id, scopes, created_at) VALUES (?, ?, ?, ?)"
+	if err := db.Query(queryCreateBot, bot.Id, bot.OwnerId, bot.Scopes, bot.CreatedAt).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the bot"})
+	}
+
+	queryUserBot := "INSERT INTO user_to_bots (owner_id, bot_id) VALUES (?, ?)"
+	if err := db.Query(queryUserBot, bot.OwnerId, bot.Id).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the bot"})
+	}
+
+	return c.Status(201).JSON(bot)
+}
+
+// GetBots lists the bots owned by the user.
+func GetBots(c *fiber.Ctx) error {
+	ownerId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the bots"})
+	}
+
+	bots, err := getUserBots(ownerId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the bots"})
+	}
+
+	return c.JSON(bots)
+}
+
+// UpdateBot changes the display name or the scopes of the bot, the tokens
+// get the new scopes within AUTH_CACHE_TTL.
+func UpdateBot(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		DisplayName *string   `json:"displayName"`
+		Scopes      *[]string `json:"scopes"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the bot's informations"})
+	}
+
+	bot, status, err := getOwnedBot(c.Locals("user_id").(string), c.Params("botId"))
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if body.DisplayName != nil {
+		displayName := strings.TrimSpace(*body.DisplayName)
+		if len([]rune(displayName)) > maxDisplayNameLength {
+			return c.Status(422).JSON(fiber.Map{"error": fmt.Sprintf("The display name can't be longer than %d characters", maxDisplayNameLength)})
+		}
+
+		if displayName != bot.DisplayName {
+			bot.DisplayName = displayName
+			if err := db.Query("UPDATE users SET displayname = ? WHERE id = ?", bot.DisplayName, bot.Id).Exec(); err != nil {
+				log.Error(err)
+				return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the bot"})
+			}
+
+			if user, err := GetUserById(bot.Id); err == nil {
+				broadcastUserUpdate(user)
+			}
+		}
+	}
+
+	if body.Scopes != nil {
+		scopes, err := validateBotScopes(*body.Scopes)
+		if err != nil {
+			return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+		}
+		bot.Scopes = scopes
+
+		if err := db.Query("UPDATE bots SET scopes = ? WHERE bot_id = ?", bot.Scopes, bot.Id).Exec(); err != nil {
+			log.Error(err)
+			return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the bot"})
+		}
+
+		tokens, err := getBotTokens(bot.Id)
+		if err != nil {
+			log.Error(err)
+		}
+		for _, token := range tokens {
+			auth.Sessions.ForgetBotToken(token.hash)
+		}
+
+		// The gateway is checked when it's opened only.
+		if !containsScope(bot.Scopes, auth.ScopeGateway) {
+			for _, token := range tokens {
+				disconnectSession(bot.Id, auth.BotSessionId(token.Id))
+			}
+		}
+	}
+
+	return c.JSON(bot)
+}
+
+// DeleteBot revokes the tokens of the bot and deletes it like an account,
+// its messages are kept under the deleted user.
+func DeleteBot(c *fiber.Ctx) error {
+	bot, status, err := getOwnedBot(c.Locals("user_id").(string), c.Params("botId"))
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user, err := GetUserById(bot.Id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Bot not found"})
+	}
+
+	applied, err := createAccountDeletion(user, ownedServersDelete)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't delete the bot"})
+	}
+
+	if !applied {
+		return c.Status(409).JSON(fiber.Map{"error": "The bot is already being deleted"})
+	}
+
+	if err := revokeBotTokens(bot.Id); err != nil {
+		log.Error(err)
+	}
+
+	go runAccountDeletion(bot.Id)
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// CreateBotToken returns a new API token for the bot, it's only shown once.
+func CreateBotToken(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Name string `json:"name"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the token's informations"})
+	}
+
+	name := strings.TrimSpace(body.Name)
+	if name == "" || len([]rune(name)) > maxTokenName {
+		return c.Status(422).JSON(fiber.Map{"error": fmt.Sprintf("A token needs a name of at most %d characters", maxTokenName)})
+	}
+
+	bot, status, err := getOwnedBot(c.Locals("user_id").(string), c.Params("botId"))
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens, err := getBotTokens(bot.Id)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the token"})
+	}
+
+	if len(tokens) >= maxTokensPerBot {
+		return c.Status(403).JSON(fiber.Map{"error": fmt.Sprintf("A bot can't have more than %d tokens", maxTokensPerBot)})
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the token"})
+	}
+
+	token := botToken{
+		BotToken: models.BotToken{
+			Id:        gocql.TimeUUID().String(),
+			Name:      name,
+			CreatedAt: time.Now(),
+			Token:     secret,
+		},
+		hash: hashToken(secret),
+	}
+
+	queryToken := "INSERT INTO bot_tokens (token_hash, bot_id, token_id) VALUES (?, ?, ?)"
+	if err := db.Query(queryToken, token.hash, bot.Id, token.Id).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the token"})
+	}
+
+	queryBotToken := "INSERT INTO bot_to_tokens (bot_id, token_id, token_hash, name, created_at) VALUES (?, ?, ?, ?, ?)"
+	if err := db.Query(queryBotToken, bot.Id, token.Id, token.hash, token.Name, token.CreatedAt).Exec(); err != nil {
+		db.Query("DELETE FROM bot_tokens WHERE token_hash = ?", token.hash).Exec()
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the token"})
+	}
+
+	return c.Status(201).JSON(token.BotToken)
+}
+
+// GetBotTokens lists the tokens of the bot, without the tokens themselves.
+func GetBotTokens(c *fiber.Ctx) error {
+	bot, status, err := getOwnedBot(c.Locals("user_id").(string), c.Params("botId"))
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens, err := getBotTokens(bot.Id)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the tokens"})
+	}
+
+	result := []models.BotToken{}
+	for _, token := range tokens {
+		result = append(result, token.BotToken)
+	}
+
+	return c.JSON(result)
+}
+
+// RevokeBotToken deletes the token and closes the gateway opened with it.
+func RevokeBotToken(c *fiber.Ctx) error {
+	bot, status, err := getOwnedBot(c.Locals("user_id").(string), c.Params("botId"))
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tokens, err := getBotTokens(bot.Id)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't revoke the token"})
+	}
+
+	for _, token := range tokens {
+		if token.Id == c.Params("tokenId") {
+			if err := revokeBotToken(bot.Id, token); err != nil {
+				log.Error(err)
+				return c.Status(500).JSON(fiber.Map{"error": "Couldn't revoke the token"})
+			}
+
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+	}
+
+	return c.Status(404).JSON(fiber.Map{"error": "Token not found"})
+}
+
+// botToken is a token with the hash it's found by.
+type botToken struct {
+	models.BotToken
+	hash string
+}
+
+// getOwnedBot returns the bot if it belongs to the user.
+func getOwnedBot(userId string, botId string) (models.Bot, int, error) {
+	db := database.DB
+	bot := models.Bot{}
+
+	queryBot := "SELECT bot_id, owner_id, scopes, created_at FROM bots WHERE bot_id = ?"
+	if err := db.Query(queryBot, botId).Scan(&bot.Id, &bot.OwnerId, &bot.Scopes, &bot.CreatedAt); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+			return bot, 500, errors.New("Couldn't fetch the bot")
+		}
+		return bot, 404, errors.New("Bot not found")
+	}
+
+	if bot.OwnerId.String() != userId {
+		return bot, 404, errors.New("Bot not found")
+	}
+
+	user, err := GetUserById(bot.Id)
+	if err != nil {
+		return bot, 404, errors.New("Bot not found")
+	}
+	bot.Username = user.Username
+	bot.DisplayName = user.DisplayName
+	bot.Avatar = user.Avatar
+
+	return bot, 200, nil
+}
+
+func getUserBots(ownerId gocql.UUID) ([]models.Bot, error) {
+	db := database.DB
+	bots := []models.Bot{}
+
+	var botIds []gocql.UUID
+	var botId gocql.UUID
+	iter := db.Query("SELECT bot_id FROM user_to_bots WHERE owner_id = ?", ownerId).Iter()
+	for iter.Scan(&botId) {
+		botIds = append(botIds, botId)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, botId := range botIds {
+		bot, _, err := getOwnedBot(ownerId.String(), botId.String())
+		if err != nil {
+			continue
+		}
+		bots = append(bots, bot)
+	}
+
+	return bots, nil
+}
+
+func getBotTokens(botId gocql.UUID) ([]botToken, error) {
+	db := database.DB
+	tokens := []botToken{}
+
+	queryTokens := "SELECT token_id, token_hash, name, created_at FROM bot_to_tokens WHERE bot_id = ?"
+	scanner := db.Query(queryTokens, botId).Iter().Scanner()
+	for scanner.Next() {
+		var token botToken
+		if err := scanner.Scan(&token.Id, &token.hash, &token.Name, &token.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, scanner.Err()
+}
+
+func revokeBotToken(botId gocql.UUID, token botToken) error {
+	db := database.DB
+
+	if err := db.Query("DELETE FROM bot_tokens WHERE token_hash = ?", token.hash).Exec(); err != nil {
+		return err
+	}
+
+	if err := db.Query("DELETE FROM bot_to_tokens WHERE bot_id = ? AND token_id = ?", botId, token.Id).Exec(); err != nil {
+		return err
+	}
+
+	auth.Sessions.ForgetBotToken(token.hash)
+	disconnectSession(botId, auth.BotSessionId(token.Id))
+	return nil
+}
+
+func revokeBotTokens(botId gocql.UUID) error {
+	tokens, err := getBotTokens(botId)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if err := revokeBotToken(botId, token); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteAccountBots is the step of the account deletion removing the bots:
+// the ones owned by a user are deleted with their own job, a bot being
+// deleted loses its tokens and its bot row.
+func deleteAccountBots(userId gocql.UUID) error {
+	db := database.DB
+
+	var botIds []gocql.UUID
+	var botId gocql.UUID
+	iter := db.Query("SELECT bot_id FROM user_to_bots WHERE owner_id = ?", userId).Iter()
+	for iter.Scan(&botId) {
+		botIds = append(botIds, botId)
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	for _, botId := range botIds {
+		user, err := GetUserById(botId)
+		if err != nil {
+			continue
+		}
+
+		if _, err := createAccountDeletion(user, ownedServersDelete); err != nil {
+			return err
+		}
+
+		if err := revokeBotTokens(botId); err != nil {
+			return err
+		}
+
+		go runAccountDeletion(botId)
+	}
+
+	if err := db.Query("DELETE FROM user_to_bots WHERE owner_id = ?", userId).Exec(); err != nil {
+		return err
+	}
+
+	var ownerId gocql.UUID
+	if err := db.Query("SELECT owner_id FROM bots WHERE bot_id = ?", userId).Scan(&ownerId); err != nil {
+		if err == gocql.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if err := revokeBotTokens(userId); err != nil {
+		return err
+	}
+
+	if err := db.Query("DELETE FROM user_to_bots WHERE owner_id = ? AND bot_id = ?", ownerId, userId).Exec(); err != nil {
+		return err
+	}
+
+	return db.Query("DELETE FROM bots WHERE bot_id = ?", userId).Exec()
+}
+
+// validateBotScopes checks the scopes are known, and sorts them without
+// duplicates.
+func validateBotScopes(scopes []string) ([]string, error) {
+	unique := map[string]bool{}
+	for _, scope := range scopes {
+		if !containsScope(auth.BotScopes, scope) {
+			return nil, fmt.Errorf("Unknown scope %q, the scopes are %s", scope, strings.Join(auth.BotScopes, ", "))
+		}
+		unique[scope] = true
+	}
+
+	result := []string{}
+	for scope := range unique {
+		result = append(result, scope)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,60 +1,42 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
-	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
-	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go/aws"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/imaging"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/storage"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 )
 
-var Client *s3.Client
-var PresignerClient Presigner
+const presignExpires = time.Duration(5) * time.Second
 
-func InitBucket() *s3.Client {
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithSharedConfigProfile("userbulles"))
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	return s3.NewFromConfig(cfg)
-}
-
-type Presigner struct {
-	PresignClient *s3.PresignClient
+// mediaSizes are the widths generated for each kind of media, the largest
+// one is stored under the key the client uploaded to.
+var mediaSizes = map[string][]int{
+	"avatar": {64, 128, 512},
+	"banner": {480, 960, 1920},
+	"icon":   {64, 128, 512},
 }
 
-func NewPresigner() {
-	Client = InitBucket()
-	PresignerClient.PresignClient = s3.NewPresignClient(Client)
-}
-
-func (presigner Presigner) PutObject(bucketName string, objectKey string) (*v4.PresignedHTTPRequest, error) {
-
-	request, err := presigner.PresignClient.PresignPutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(objectKey),
-	}, func(opts *s3.PresignOptions) {
-		opts.Expires = time.Duration(5) * time.Second
-	})
+// presignPut keeps the shape of the presigned request the client already
+// reads the URL from.
+func presignPut(objectKey string) (fiber.Map, error) {
+	url, err := storage.Store.PresignPut(objectKey, presignExpires)
 	if err != nil {
-		log.Errorf("Couldn't get a presigned request to put %v:%v. Here's why: %v\n",
-			bucketName, objectKey, err)
+		return nil, err
 	}
-	return request, err
 
+	return fiber.Map{"URL": url, "Method": fiber.MethodPut}, nil
 }
 
 func PutObjectInS3Bucket(c *fiber.Ctx) error {
-	bucketName := c.Params("bucketName")
 	folder := c.Params("folder")
 	media := c.Params("media")
 	version := c.Params("version")
@@ -66,10 +48,18 @@ func PutObjectInS3Bucket(c *fiber.Ctx) error {
 		userId = c.Locals("user_id").(string)
 		objectKey = fmt.Sprintf("%s/%s_%s_v%s.webp", folder, media, userId, version)
 	} else {
+		server, err := utils.GetServerInformations(entity)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Server not found"})
+		}
+
+		if !canManageServer(server, c.Locals("user_id").(string)) {
+			return c.Status(403).JSON(fiber.Map{"error": "You can't change the medias of this server"})
+		}
 		objectKey = fmt.Sprintf("%s/%s_%s_v%s.webp", folder, media, entity, version)
 	}
 
-	url, err := PresignerClient.PutObject(bucketName, objectKey)
+	url, err := presignPut(objectKey)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the presigned URL"})
 	}
@@ -87,15 +77,21 @@ func UpdateMediaForUser(c *fiber.Ctx) error {
 
 	user_id := c.Locals("user_id").(string)
 
-	url := fmt.Sprintf("%suser_%s/%s_%s_v%s.webp", env.Variable("CLOUDFRONT_URL"), media, media, user_id, version)
+	if media != "avatar" && media != "banner" {
+		return c.Status(422).JSON(fiber.Map{"error": "Unknown media"})
+	}
+	sizes := mediaSizes[media]
+
+	newObjectKey := fmt.Sprintf("user_%s/%s_%s_v%s.webp", media, media, user_id, version)
+	if err := processMedia(newObjectKey, sizes); err != nil {
+		return mediaError(c, err)
+	}
+
+	url := storage.Store.URL(newObjectKey)
 
-	bucketName := "bulles-bucket"
 	objectKey := fmt.Sprintf("user_%s/%s_%s_v%s.webp", media, media, user_id, oldVersionStr)
 
-	_, err := Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(objectKey),
-	})
+	err := deleteMedia(objectKey, sizes)
 	if err != nil {
 		log.Errorf("Error when deleting old media: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Couldn't delete old media"})
@@ -109,3 +105,77 @@ func UpdateMediaForUser(c *fiber.Ctx) error {
 
 	return c.Status(200).JSON(fiber.Map{"url": url})
 }
+
+// processMedia replaces the file uploaded by the client with a checked webp
+// without metadata, and stores the resized variants next to it. The upload
+// is removed when it isn't a valid image.
+func processMedia(objectKey string, sizes []int) error {
+	// The presigned PUT doesn't limit the size, it's checked before anything
+	// is downloaded.
+	info, err := storage.Store.Head(objectKey)
+	if err != nil {
+		return err
+	}
+
+	if info.Size > imaging.MaxBytes {
+		discardMedia(objectKey)
+		return imaging.ErrTooLarge
+	}
+
+	data, err := storage.Store.Get(objectKey, imaging.MaxBytes)
+	if err != nil {
+		return err
+	}
+
+	result, err := imaging.Process(data, sizes)
+	if err != nil {
+		if errors.Is(err, imaging.ErrInvalidImage) || errors.Is(err, imaging.ErrTooLarge) {
+			discardMedia(objectKey)
+		}
+		return err
+	}
+
+	for _, variant := range result.Variants {
+		if err := storage.Store.Put(mediaVariantKey(objectKey, variant.Size), variant.Data, "image/webp"); err != nil {
+			return err
+		}
+	}
+
+	return storage.Store.Put(objectKey, result.Largest().Data, "image/webp")
+}
+
+func discardMedia(objectKey string) {
+	if err := storage.Store.Delete(objectKey); err != nil {
+		log.Error(err)
+	}
+}
+
+func deleteMedia(objectKey string, sizes []int) error {
+	for _, size := range sizes {
+		if err := storage.Store.Delete(mediaVariantKey(objectKey, size)); err != nil {
+			return err
+		}
+	}
+
+	return storage.Store.Delete(objectKey)
+}
+
+// mediaVariantKey gives the key of a resized variant, e.g.
+// user_avatar/avatar_<id>_v2_128.webp for user_avatar/avatar_<id>_v2.webp.
+func mediaVariantKey(objectKey string, size int) string {
+	return fmt.Sprintf("%s_%d.webp", strings.TrimSuffix(objectKey, ".webp"), size)
+}
+
+func mediaError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "The media hasn't been uploaded"})
+	case errors.Is(err, imaging.ErrInvalidImage):
+		return c.Status(422).JSON(fiber.Map{"error": "The media is not a valid image"})
+	case errors.Is(err, imaging.ErrTooLarge):
+		return c.Status(413).JSON(fiber.Map{"error": "The media is too large"})
+	}
+
+	log.Errorf("Error when processing the media: %v", err)
+	return c.Status(500).JSON(fiber.Map{"error": "Couldn't process the media"})
+}
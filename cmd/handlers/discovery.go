@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	serverStatusPublic  = "public"
+	serverStatusPrivate = "private"
+
+	maxServerTags        = 5
+	maxServerTagLength   = 24
+	defaultDiscoveryPage = 25
+	maxDiscoveryPage     = 50
+	maxDiscoveryScan     = 500
+)
+
+var serverTagRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// UpdateServerDiscovery lets the owner list the server in the directory, or
+// take it out of it, along with the tags and the category it's found by.
+func UpdateServerDiscovery(c *fiber.Ctx) error {
+	db := database.DB
+	serverId := c.Params("serverId")
+	type BodyRequest struct {
+		Public   bool     `json:"public"`
+		Category string   `json:"category"`
+		Tags     []string `json:"tags"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the server's informations"})
+	}
+
+	server, err := utils.GetServerInformations(serverId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Server not found"})
+	}
+
+	userId := c.Locals("user_id").(string)
+	if !canManageServer(server, userId) {
+		return c.Status(403).JSON(fiber.Map{"error": "You can't change the settings of this server"})
+	}
+
+	tags, ok := normalizeServerTags(body.Tags)
+	if !ok {
+		return c.Status(422).JSON(fiber.Map{"error": "Invalid tags"})
+	}
+
+	before := server
+	server.Category = strings.ToLower(strings.TrimSpace(body.Category))
+	server.Tags = tags
+	server.Status = serverStatusPrivate
+	if body.Public {
+		server.Status = serverStatusPublic
+	}
+
+	queryUpdateServer := "UPDATE servers SET status = ?, category = ?, tags = ? WHERE server_id = ?"
+	if err := db.Query(queryUpdateServer, server.Status, server.Category, server.Tags, serverId).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the server"})
+	}
+
+	if body.Public {
+		queryListServer := "INSERT INTO public_servers (server_id, name, description, icon, banner, category, tags, member_count) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+		err = db.Query(queryListServer, serverId, server.Name, server.Description, server.Icon, server.Banner, server.Category, server.Tags, len(getServerMembers(serverId))).Exec()
+	} else {
+		err = db.Query("DELETE FROM public_servers WHERE server_id = ?", serverId).Exec()
+	}
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the server directory"})
+	}
+
+	recordAudit(serverId, userId, auditActionServerUpdate, "server", serverId, before, server)
+
+	return c.JSON(server)
+}
+
+// DiscoverServers browses the public servers. ?q= searches the names, ?tag=
+// and ?category= filter, and ?limit= sets the size of a page.
+//
+// The directory holds everything shown about a server, it's read in its
+// storage order and filtered here, no more than maxDiscoveryScan rows per
+// request. The response carries a "next" token to pass as ?page= for the
+// following page, which may be short or even empty when few servers match,
+// the end of the directory is reached when there's no token.
+func DiscoverServers(c *fiber.Ctx) error {
+	db := database.DB
+	search := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	tag := strings.ToLower(c.Query("tag"))
+	category := strings.ToLower(c.Query("category"))
+
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultDiscoveryPage)))
+	if err != nil || limit <= 0 || limit > maxDiscoveryPage {
+		limit = defaultDiscoveryPage
+	}
+
+	pageState, skip, err := parseDiscoveryToken(c.Query("page"))
+	if err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": "Invalid page"})
+	}
+
+	servers := []models.DiscoverableServer{}
+	next := ""
+	scanned := 0
+
+	queryServers := "SELECT server_id, name, description, icon, banner, category, tags, member_count FROM public_servers"
+	for {
+		iter := db.Query(queryServers).PageSize(maxDiscoveryPage).PageState(pageState).Iter()
+		nextPageState := iter.PageState()
+		scanner := iter.Scanner()
+
+		row := 0
+		for next == "" && scanner.Next() {
+			row++
+			if row <= skip {
+				continue
+			}
+
+			var server models.DiscoverableServer
+			if err := scanner.Scan(&server.ServerId, &server.Name, &server.Description, &server.Icon, &server.Banner, &server.Category, &server.Tags, &server.MemberCount); err != nil {
+				log.Error(err)
+				continue
+			}
+			scanned++
+
+			if matchesDiscovery(server, search, tag, category) {
+				servers = append(servers, server)
+			}
+
+			// The rest of this page is read again by the next request.
+			if len(servers) == limit {
+				next = discoveryToken(pageState, row)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			log.Error(err)
+			return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the servers"})
+		}
+
+		if next != "" || len(nextPageState) == 0 {
+			break
+		}
+
+		pageState, skip = nextPageState, 0
+		if scanned >= maxDiscoveryScan {
+			next = discoveryToken(pageState, 0)
+			break
+		}
+	}
+
+	return c.JSON(fiber.Map{"servers": servers, "next": next})
+}
+
+func matchesDiscovery(server models.DiscoverableServer, search string, tag string, category string) bool {
+	if category != "" && server.Category != category {
+		return false
+	}
+
+	if tag != "" && !containsString(server.Tags, tag) {
+		return false
+	}
+
+	return search == "" || strings.Contains(strings.ToLower(server.Name), search)
+}
+
+// A discovery token is the paging state of the directory and the number of
+// rows of that page already returned.
+func discoveryToken(pageState []byte, skip int) string {
+	return base64.RawURLEncoding.EncodeToString(pageState) + "." + strconv.Itoa(skip)
+}
+
+func parseDiscoveryToken(token string) ([]byte, int, error) {
+	if token == "" {
+		return nil, 0, nil
+	}
+
+	encodedState, encodedSkip, found := strings.Cut(token, ".")
+	if !found {
+		return nil, 0, fmt.Errorf("invalid discovery token")
+	}
+
+	pageState, err := base64.RawURLEncoding.DecodeString(encodedState)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip, err := strconv.Atoi(encodedSkip)
+	if err != nil || skip < 0 || skip > maxDiscoveryPage {
+		return nil, 0, fmt.Errorf("invalid discovery token")
+	}
+
+	return pageState, skip, nil
+}
+
+// refreshServerListing copies the name, medias and member count of a listed
+// server into the directory, after they changed.
+func refreshServerListing(serverId string) {
+	db := database.DB
+
+	server, err := utils.GetServerInformations(serverId)
+	if err != nil || server.Status != serverStatusPublic {
+		return
+	}
+
+	queryRefreshListing := "UPDATE public_servers SET name = ?, description = ?, icon = ?, banner = ?, member_count = ? WHERE server_id = ? IF EXISTS"
+	if _, err := db.Query(queryRefreshListing, server.Name, server.Description, server.Icon, server.Banner, len(getServerMembers(serverId)), serverId).MapScanCAS(map[string]interface{}{}); err != nil {
+		log.Error(err)
+	}
+}
+
+// JoinPublicServer joins a public server without any invitation.
+func JoinPublicServer(c *fiber.Ctx) error {
+	serverId := c.Params("serverId")
+	userId := c.Locals("user_id").(string)
+
+	server, err := utils.GetServerInformations(serverId)
+	if err != nil || server.Status != serverStatusPublic {
+		return c.Status(404).JSON(fiber.Map{"error": "Server not found"})
+	}
+
+	userUUID, err := gocql.ParseUUID(userId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't join the server"})
+	}
+
+	if containsUUID(getServerMembers(serverId), userUUID) {
+		return c.Status(409).JSON(fiber.Map{"error": "You are already in this server"})
+	}
+
+	return joinServer(c, userId, serverId, map[string]interface{}{"discovery": true})
+}
+
+func getServerMembers(serverId string) []gocql.UUID {
+	db := database.DB
+	var users []gocql.UUID
+
+	queryGetUsersOfServer := "SELECT users FROM server_to_users WHERE server_id = ?"
+	if err := db.Query(queryGetUsersOfServer, serverId).Scan(&users); err != nil && err != gocql.ErrNotFound {
+		log.Error(err)
+	}
+
+	return users
+}
+
+func normalizeServerTags(tags []string) ([]string, bool) {
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || containsString(normalized, tag) {
+			continue
+		}
+
+		if len(tag) > maxServerTagLength || !serverTagRegex.MatchString(tag) {
+			return nil, false
+		}
+		normalized = append(normalized, tag)
+	}
+
+	return normalized, len(normalized) <= maxServerTags
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	dmTypeDirect = "dm"
+	dmTypeGroup  = "group"
+)
+
+// OpenDM returns the 1:1 channel between the caller and the requested user,
+// creating it the first time. Calling it again for the same pair always
+// returns the same channel.
+func OpenDM(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		UserId string `json:"user_id"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when opening the conversation"})
+	}
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Error when opening the conversation"})
+	}
+
+	partnerId, err := gocql.ParseUUID(body.UserId)
+	if err != nil || partnerId == userId {
+		return c.Status(422).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	partner, err := GetUserById(partnerId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if !areFriends(db, userId, partnerId) {
+		return c.Status(403).JSON(fiber.Map{"error": "The two users are not friends"})
+	}
+
+	// The pair is stored in a fixed order so both users hit the same row,
+	// and the LWT makes concurrent opens agree on a single channel.
+	first, second := userId, partnerId
+	if first.String() > second.String() {
+		first, second = second, first
+	}
+
+	channelId := utils.GenerateNanoid()
+	existing := make(map[string]interface{})
+	queryCreateDM := "INSERT INTO dm_channels (first_user, second_user, channel_id) VALUES (?, ?, ?) IF NOT EXISTS"
+	applied, err := db.Query(queryCreateDM, first, second, channelId).MapScanCAS(existing)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't open the conversation"})
+	}
+
+	if !applied {
+		channelId = existing["channel_id"].(string)
+	} else {
+		t := time.Now()
+
+		queryJoinChannel := "INSERT INTO channel_to_users (channel_id, user_id) VALUES (?, ?)"
+		queryAddToList := "INSERT INTO user_to_dms (user_id, channel_id, partner_id, last_activity, type) VALUES (?, ?, ?, ?, ?)"
+		for _, pair := range [][2]gocql.UUID{{userId, partnerId}, {partnerId, userId}} {
+			if err := db.Query(queryJoinChannel, channelId, pair[0]).Exec(); err != nil {
+				log.Error(err)
+				return c.Status(500).JSON(fiber.Map{"error": "Couldn't open the conversation"})
+			}
+
+			if err := db.Query(queryAddToList, pair[0], channelId, pair[1], t, dmTypeDirect).Exec(); err != nil {
+				log.Error(err)
+				return c.Status(500).JSON(fiber.Map{"error": "Couldn't open the conversation"})
+			}
+		}
+	}
+
+	dm := models.DirectMessage{Type: dmTypeDirect}
+	queryGetDM := "SELECT channel_id, partner_id, last_activity FROM user_to_dms WHERE user_id = ? AND channel_id = ?"
+	if err := db.Query(queryGetDM, userId, channelId).Scan(&dm.ChannelId, &dm.PartnerId, &dm.LastActivity); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't open the conversation"})
+	}
+	dm.User = partner
+
+	return c.JSON(dm)
+}
+
+func GetDMs(c *fiber.Ctx) error {
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch your conversations"})
+	}
+
+	dms, err := getDirectMessages(userId)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch your conversations"})
+	}
+
+	return c.JSON(dms)
+}
+
+// getDirectMessages lists the DM and group DM channels of a user, most
+// recently active first.
+func getDirectMessages(userId gocql.UUID) ([]models.DirectMessage, error) {
+	db := database.DB
+	dms := []models.DirectMessage{}
+
+	queryGetDMs := "SELECT channel_id, partner_id, last_activity, type FROM user_to_dms WHERE user_id = ?"
+	scanner := db.Query(queryGetDMs, userId).Iter().Scanner()
+	for scanner.Next() {
+		var dm models.DirectMessage
+		if err := scanner.Scan(&dm.ChannelId, &dm.PartnerId, &dm.LastActivity, &dm.Type); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+
+		if dm.Type == dmTypeGroup {
+			group, err := getGroupDM(dm.ChannelId)
+			if err != nil {
+				continue
+			}
+			group.LastActivity = dm.LastActivity
+			dms = append(dms, group)
+			continue
+		}
+
+		partner, err := GetUserById(dm.PartnerId)
+		if err != nil {
+			continue
+		}
+		dm.Type = dmTypeDirect
+		dm.User = partner
+
+		dms = append(dms, dm)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	sort.Slice(dms, func(i, j int) bool {
+		return dms[i].LastActivity.After(dms[j].LastActivity)
+	})
+
+	return dms, nil
+}
+
+func touchDM(db *gocql.Session, users []gocql.UUID, channelId string, t time.Time) {
+	queryTouch := "UPDATE user_to_dms SET last_activity = ? WHERE user_id = ? AND channel_id = ? IF EXISTS"
+	for _, userId := range users {
+		if err := db.Query(queryTouch, t, userId, channelId).Exec(); err != nil {
+			log.Errorf("Error when updating the DM activity: %v", err)
+		}
+	}
+}
+
+func areFriends(db *gocql.Session, userId gocql.UUID, friendId gocql.UUID) bool {
+	var id gocql.UUID
+	queryCheckFriend := "SELECT friend_id FROM friends WHERE user_id = ? AND friend_id = ?"
+	if err := db.Query(queryCheckFriend, userId, friendId).Scan(&id); err != nil {
+		return false
+	}
+
+	return true
+}
+
+func directMessageToProtobuf(dm models.DirectMessage) *protobuf.DirectMessage {
+	message := &protobuf.DirectMessage{
+		ChannelId:    dm.ChannelId,
+		Type:         dm.Type,
+		LastActivity: timestamppb.New(dm.LastActivity),
+	}
+
+	if dm.Type == dmTypeGroup {
+		message.Name = dm.Name
+		message.Icon = dm.Icon
+		message.Owner = dm.Owner.String()
+		for _, user := range dm.Users {
+			message.Users = append(message.Users, userToProtobuf(user))
+		}
+	} else {
+		message.User = userToProtobuf(dm.User)
+	}
+
+	return message
+}
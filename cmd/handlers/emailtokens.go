@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/mailer"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// The tokens mailed to the users, stored hashed in email_tokens and only
+// usable once.
+const (
+	emailTokenVerify        = "verify_email"
+	emailTokenResetPassword = "reset_password"
+	verifyEmailDuration     = 48 * time.Hour
+	resetPasswordDuration   = time.Hour
+	minPasswordLength       = 8
+)
+
+// VerifyEmail marks the address of the user as verified, the token must have
+// been sent to the address the account still has.
+func VerifyEmail(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Token string `json:"token"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the token"})
+	}
+
+	userId, email, err := useEmailToken(body.Token, emailTokenVerify)
+	if err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return c.Status(400).JSON(fiber.Map{"error": "This link is invalid or has expired"})
+	}
+
+	user, err := GetUserById(userId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.Email != email {
+		return c.Status(400).JSON(fiber.Map{"error": "This link is invalid or has expired"})
+	}
+
+	queryVerify := "UPDATE users SET email_verified = true WHERE id = ?"
+	if err := db.Query(queryVerify, userId).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't verify the email address"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func ResendVerificationEmail(c *fiber.Ctx) error {
+	user, err := GetUserById(c.Locals("user_id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.EmailVerified {
+		return c.Status(409).JSON(fiber.Map{"error": "Your email address is already verified"})
+	}
+
+	if err := sendVerificationEmail(user); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't send the verification email"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RequestPasswordReset mails a reset link to the address. The answer is the
+// same whether an account uses it or not, and comes before the account is
+// even looked up so its timing doesn't tell either.
+func RequestPasswordReset(c *fiber.Ctx) error {
+	type BodyRequest struct {
+		Email string `json:"email"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the email address"})
+	}
+
+	email := strings.TrimSpace(body.Email)
+	go func() {
+		user, err := getUserByEmail(email)
+		if err != nil {
+			return
+		}
+
+		if err := sendPasswordResetEmail(user); err != nil {
+			log.Errorf("Error when sending the password reset email: %v", err)
+		}
+	}()
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// ConfirmPasswordReset sets the new password and logs out every session of
+// the account.
+func ConfirmPasswordReset(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the request"})
+	}
+
+	if len(body.Password) < minPasswordLength {
+		return c.Status(422).JSON(fiber.Map{"error": fmt.Sprintf("The password must be at least %d characters long", minPasswordLength)})
+	}
+
+	userId, email, err := useEmailToken(body.Token, emailTokenResetPassword)
+	if err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return c.Status(400).JSON(fiber.Map{"error": "This link is invalid or has expired"})
+	}
+
+	user, err := GetUserById(userId)
+	if err != nil || user.Email != email {
+		return c.Status(400).JSON(fiber.Map{"error": "This link is invalid or has expired"})
+	}
+
+	hash, err := hashPassword(body.Password)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't reset the password"})
+	}
+
+	// Receiving the link proves the address belongs to the user as well.
+	queryResetPassword := "UPDATE users SET password = ?, email_verified = true WHERE id = ?"
+	if err := db.Query(queryResetPassword, hash, userId).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't reset the password"})
+	}
+
+	if _, err := revokeAllSessions(userId, ""); err != nil {
+		log.Error(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func sendVerificationEmail(user models.User) error {
+	token, err := createEmailToken(user, emailTokenVerify, verifyEmailDuration)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Mail.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Hi %s,\n\nConfirm this is your email address by opening this link:\n%s\n\nThe link expires in %d hours.",
+			user.Username, appLink("/verify-email", token), int(verifyEmailDuration.Hours())),
+	})
+}
+
+func sendPasswordResetEmail(user models.User) error {
+	token, err := createEmailToken(user, emailTokenResetPassword, resetPasswordDuration)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Mail.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. Choose a new one with this link:\n%s\n\nThe link expires in %d minutes. If it wasn't you, you can ignore this email.",
+			user.Username, appLink("/reset-password", token), int(resetPasswordDuration.Minutes())),
+	})
+}
+
+func createEmailToken(user models.User, purpose string, duration time.Duration) (string, error) {
+	db := database.DB
+
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	queryToken := "INSERT INTO email_tokens (token_hash, user_id, purpose, email) VALUES (?, ?, ?, ?) USING TTL ?"
+	if err := db.Query(queryToken, hashToken(token), user.Id, purpose, user.Email, int(duration.Seconds())).Exec(); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// useEmailToken consumes the token and returns the user and the address it
+// was sent to, gocql.ErrNotFound when it's unknown, expired or already used.
+func useEmailToken(token string, purpose string) (gocql.UUID, string, error) {
+	db := database.DB
+	var userId gocql.UUID
+	var tokenPurpose, email string
+
+	tokenHash := hashToken(token)
+	queryToken := "SELECT user_id, purpose, email FROM email_tokens WHERE token_hash = ?"
+	if err := db.Query(queryToken, tokenHash).Scan(&userId, &tokenPurpose, &email); err != nil {
+		return userId, "", err
+	}
+
+	if tokenPurpose != purpose {
+		return userId, "", gocql.ErrNotFound
+	}
+
+	queryUseToken := "DELETE FROM email_tokens WHERE token_hash = ? IF EXISTS"
+	applied, err := db.Query(queryUseToken, tokenHash).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		return userId, "", err
+	}
+
+	if !applied {
+		return userId, "", gocql.ErrNotFound
+	}
+
+	return userId, email, nil
+}
+
+// appLink is a link to the page of the client, APP_URL being where it's
+// served.
+func appLink(path string, token string) string {
+	return appURL(path) + "?token=" + url.QueryEscape(token)
+}
+
+func appURL(path string) string {
+	base := env.Variable("APP_URL")
+	if base == "" {
+		base = "https://localhost:5173"
+	}
+
+	return strings.TrimRight(base, "/") + path
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/unfurl"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	maxEmbedsByMessage = 5
+	unfurlTimeout      = 10 * time.Second
+	// Links without anything to show are cached too, for less time, so
+	// they're not fetched again for every message.
+	embedCacheTTL       = 24 * time.Hour
+	embedNegativeTTL    = time.Hour
+	unfurlQueueCapacity = 256
+)
+
+var unfurler *unfurl.Unfurler
+var unfurlQueue = make(chan models.Message, unfurlQueueCapacity)
+
+// StartUnfurlWorkers starts the goroutines fetching the embeds of the links
+// posted in messages. UNFURL_ALLOW_PRIVATE lets them reach private addresses,
+// only for running against a local HTTP stand-in.
+func StartUnfurlWorkers(workers int) {
+	unfurler = unfurl.New(unfurl.Config{
+		AllowPrivate: env.Variable("UNFURL_ALLOW_PRIVATE") == "true",
+	})
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for message := range unfurlQueue {
+				unfurlMessage(message)
+			}
+		}()
+	}
+}
+
+// queueUnfurl hands the message to the workers when it contains links. The
+// message is sent without waiting, the embeds follow in their own event.
+func queueUnfurl(message models.Message) {
+	if unfurler == nil || len(utils.ParseLinks(message.Content, maxEmbedsByMessage)) == 0 {
+		return
+	}
+
+	select {
+	case unfurlQueue <- message:
+	default:
+		log.Warnf("Unfurl queue is full, no embeds for message %s", message.MessageId)
+	}
+}
+
+func unfurlMessage(message models.Message) {
+	db := database.DB
+	var embeds []models.Embed
+
+	for _, link := range utils.ParseLinks(message.Content, maxEmbedsByMessage) {
+		embed, ok := getEmbed(link)
+		if ok {
+			embeds = append(embeds, embed)
+		}
+	}
+
+	if len(embeds) == 0 {
+		return
+	}
+
+	queryCreateEmbed := "INSERT INTO message_embeds (message_id, position, url, type, title, description, site_name, image) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	for position, embed := range embeds {
+		if err := db.Query(queryCreateEmbed, message.MessageId, position, embed.Url, embed.Type, embed.Title, embed.Description, embed.SiteName, embed.Image).Exec(); err != nil {
+			log.Error(err)
+			return
+		}
+	}
+
+	var protobufEmbeds []*protobuf.Embed
+	for _, embed := range embeds {
+		protobufEmbeds = append(protobufEmbeds, embedToProtobuf(embed))
+	}
+
+	messageToSend := &protobuf.ServerMessage{
+		Type: "message_embeds_updated",
+		Payload: &protobuf.ServerMessage_MessageEmbedsUpdated{
+			MessageEmbedsUpdated: &protobuf.MessageEmbedsUpdated{
+				ChannelId: message.ChannelId,
+				MessageId: message.MessageId.String(),
+				Embeds:    protobufEmbeds,
+			},
+		},
+	}
+
+	data, err := proto.Marshal(messageToSend)
+	if err != nil {
+		fmt.Println("Error when transforming the message into protobuf", err)
+		return
+	}
+
+	for _, userId := range getAllUsersFromChannel(message.ChannelId, db) {
+		sendToUser(userId, data, nil)
+	}
+}
+
+// getEmbed returns the embed of the link from the cache, or fetches it. The
+// boolean is false when the link has nothing to show.
+func getEmbed(link string) (models.Embed, bool) {
+	db := database.DB
+	var embed models.Embed
+
+	queryCachedEmbed := "SELECT url, type, title, description, site_name, image FROM embed_cache WHERE url = ?"
+	err := db.Query(queryCachedEmbed, link).Scan(&embed.Url, &embed.Type, &embed.Title, &embed.Description, &embed.SiteName, &embed.Image)
+	if err == nil {
+		return embed, embed.Type != ""
+	} else if err != gocql.ErrNotFound {
+		log.Error(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), unfurlTimeout)
+	defer cancel()
+
+	ttl := embedCacheTTL
+	fetched, err := unfurler.Unfurl(ctx, link)
+	if err != nil {
+		log.Infof("No embed for %s: %v", link, err)
+		fetched = unfurl.Embed{Url: link}
+		ttl = embedNegativeTTL
+	}
+	embed = models.Embed(fetched)
+
+	queryCacheEmbed := "INSERT INTO embed_cache (url, type, title, description, site_name, image) VALUES (?, ?, ?, ?, ?, ?) USING TTL ?"
+	if err := db.Query(queryCacheEmbed, link, embed.Type, embed.Title, embed.Description, embed.SiteName, embed.Image, int(ttl.Seconds())).Exec(); err != nil {
+		log.Error(err)
+	}
+
+	return embed, embed.Type != ""
+}
+
+func getMessageEmbeds(messageId gocql.UUID) []models.Embed {
+	db := database.DB
+	embeds := []models.Embed{}
+
+	queryEmbeds := "SELECT url, type, title, description, site_name, image FROM message_embeds WHERE message_id = ?"
+	scanner := db.Query(queryEmbeds, messageId).Iter().Scanner()
+	for scanner.Next() {
+		var embed models.Embed
+		if err := scanner.Scan(&embed.Url, &embed.Type, &embed.Title, &embed.Description, &embed.SiteName, &embed.Image); err != nil {
+			log.Error(err)
+			continue
+		}
+		embeds = append(embeds, embed)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+	}
+
+	return embeds
+}
+
+func embedToProtobuf(embed models.Embed) *protobuf.Embed {
+	return &protobuf.Embed{
+		Url:         embed.Url,
+		Type:        embed.Type,
+		Title:       embed.Title,
+		Description: embed.Description,
+		SiteName:    embed.SiteName,
+		Image:       embed.Image,
+	}
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/storage"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	exportStatusPending = "pending"
+	exportStatusReady   = "ready"
+	exportStatusFailed  = "failed"
+
+	// The archives are private objects, only reachable through a link
+	// presigned for exportLinkDuration. A new export can be requested once
+	// a day.
+	exportLinkDuration  = 24 * time.Hour
+	exportInterval      = 24 * time.Hour
+	exportRetention     = 7 * 24 * time.Hour
+	exportSweepInterval = time.Hour
+	exportPrefix        = "private/exports/"
+)
+
+// RequestDataExport starts gathering everything held about the user into a
+// ZIP archive, the user is notified over the websocket once it's ready.
+func RequestDataExport(c *fiber.Ctx) error {
+	db := database.DB
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't start the export"})
+	}
+
+	exports, err := getDataExports(userId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't start the export"})
+	}
+
+	if len(exports) > 0 {
+		last := exports[0]
+		// An export still pending after an hour was lost in a restart.
+		if last.Status == exportStatusPending && time.Since(last.CreatedAt) < time.Hour {
+			return c.Status(409).JSON(fiber.Map{"error": "An export is already being prepared"})
+		}
+
+		if last.Status == exportStatusReady && time.Since(last.CreatedAt) < exportInterval {
+			return c.Status(429).JSON(fiber.Map{"error": "You can only request one export a day"})
+		}
+	}
+
+	export := models.DataExport{
+		Id:        gocql.UUIDFromTime(time.Now()),
+		UserId:    userId,
+		Status:    exportStatusPending,
+		CreatedAt: time.Now(),
+	}
+
+	queryCreateExport := "INSERT INTO data_exports (user_id, export_id, status, created_at) VALUES (?, ?, ?, ?) USING TTL ?"
+	if err := db.Query(queryCreateExport, export.UserId, export.Id, export.Status, export.CreatedAt, int(exportRetention.Seconds())).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't start the export"})
+	}
+
+	// Only the latest archive is kept.
+	for _, previous := range exports {
+		if previous.ObjectKey != "" {
+			if err := storage.Store.Delete(previous.ObjectKey); err != nil {
+				log.Error(err)
+			}
+		}
+	}
+
+	go runDataExport(export)
+
+	return c.Status(fiber.StatusAccepted).JSON(export)
+}
+
+// GetDataExports lists the exports of the user, the most recent first as
+// they're clustered by descending timeuuid, with a fresh download link for
+// the ready ones.
+func GetDataExports(c *fiber.Ctx) error {
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the exports"})
+	}
+
+	exports, err := getDataExports(userId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the exports"})
+	}
+
+	for i := range exports {
+		if exports[i].Status != exportStatusReady {
+			continue
+		}
+
+		url, err := storage.Store.PresignGet(exports[i].ObjectKey, exportLinkDuration)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		expiresAt := time.Now().Add(exportLinkDuration)
+		exports[i].URL = url
+		exports[i].ExpiresAt = &expiresAt
+	}
+
+	return c.JSON(exports)
+}
+
+func getDataExports(userId gocql.UUID) ([]models.DataExport, error) {
+	db := database.DB
+	exports := []models.DataExport{}
+
+	queryExports := "SELECT export_id, status, object_key, created_at, completed_at FROM data_exports WHERE user_id = ?"
+	scanner := db.Query(queryExports, userId).Iter().Scanner()
+	for scanner.Next() {
+		export := models.DataExport{UserId: userId}
+		if err := scanner.Scan(&export.Id, &export.Status, &export.ObjectKey, &export.CreatedAt, &export.CompletedAt); err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, scanner.Err()
+}
+
+// StartExportSweeper deletes the archives older than exportRetention, their
+// row in data_exports is gone by then and nothing else would.
+func StartExportSweeper() {
+	go func() {
+		ticker := time.NewTicker(exportSweepInterval)
+		defer ticker.Stop()
+
+		sweepDataExports(time.Now())
+		for now := range ticker.C {
+			sweepDataExports(now)
+		}
+	}()
+}
+
+func sweepDataExports(now time.Time) {
+	objects, err := storage.Store.List(exportPrefix)
+	if err != nil {
+		log.Errorf("Error when listing the exports: %v", err)
+		return
+	}
+
+	for _, object := range objects {
+		if now.Sub(object.ModifiedAt) < exportRetention {
+			continue
+		}
+
+		if err := storage.Store.Delete(object.Key); err != nil {
+			log.Errorf("Error when deleting the export %s: %v", object.Key, err)
+		}
+	}
+}
+
+func runDataExport(export models.DataExport) {
+	db := database.DB
+
+	archive, err := buildDataExport(export.UserId)
+	if err == nil {
+		export.ObjectKey = fmt.Sprintf("%s%s/%s.zip", exportPrefix, export.UserId, export.Id)
+		err = storage.Store.Put(export.ObjectKey, archive, "application/zip")
+	}
+
+	export.Status = exportStatusReady
+	export.CompletedAt = time.Now()
+	if err != nil {
+		log.Errorf("Error when exporting the data of %s: %v", export.UserId, err)
+		export.Status = exportStatusFailed
+	}
+
+	queryCompleteExport := "UPDATE data_exports USING TTL ? SET status = ?, object_key = ?, completed_at = ? WHERE user_id = ? AND export_id = ?"
+	if err := db.Query(queryCompleteExport, int(exportRetention.Seconds()), export.Status, export.ObjectKey, export.CompletedAt, export.UserId, export.Id).Exec(); err != nil {
+		log.Error(err)
+		return
+	}
+
+	if export.Status == exportStatusReady {
+		notifyDataExport(export)
+	}
+}
+
+// buildDataExport packs the profile, servers, friends, sessions and the
+// messages written by the user as JSON files in a ZIP archive.
+func buildDataExport(userId gocql.UUID) ([]byte, error) {
+	db := database.DB
+
+	user, err := GetUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var serversId []string
+	queryGetServers := "SELECT servers FROM user_to_servers WHERE user_id = ?"
+	if err := db.Query(queryGetServers, userId).Scan(&serversId); err != nil && err != gocql.ErrNotFound {
+		return nil, err
+	}
+
+	servers := []models.Server{}
+	for _, serverId := range serversId {
+		server, err := utils.GetServerInformations(serverId)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		servers = append(servers, server)
+	}
+
+	friends := []models.User{}
+	var friendId gocql.UUID
+	iter := db.Query("SELECT friend_id FROM friends WHERE user_id = ?", userId).Iter()
+	for iter.Scan(&friendId) {
+		friend, err := GetUserById(friendId)
+		if err != nil {
+			continue
+		}
+		friends = append(friends, models.User{Id: friend.Id, Username: friend.Username, DisplayName: friend.DisplayName})
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	sessions := []models.Session{}
+	scanner := db.Query("SELECT session_id, timezone, user_agent, created_at, last_seen FROM user_sessions WHERE user_id = ?", userId).Iter().Scanner()
+	for scanner.Next() {
+		var session models.Session
+		if err := scanner.Scan(&session.SessionId, &session.Timezone, &session.UserAgent, &session.CreatedAt, &session.LastSeen); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	messages, err := getAuthoredMessages(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for name, content := range map[string]interface{}{
+		"profile.json":  user,
+		"servers.json":  servers,
+		"friends.json":  friends,
+		"sessions.json": sessions,
+		"messages.json": messages,
+	} {
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := file.Write(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// getAuthoredMessages goes through the partitions of every channel the user
+// wrote in, including the ones they left, the messages aren't indexed by
+// sender.
+func getAuthoredMessages(userId gocql.UUID) ([]models.Message, error) {
+	db := database.DB
+	messages := []models.Message{}
+
+	channels, err := getAuthoredChannels(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	queryMessages := "SELECT channel_id, created_at, message_id, content, attachments, server_id FROM messages WHERE channel_id = ? AND sender_id = ? ALLOW FILTERING"
+	for _, channelId := range channels {
+		scanner := db.Query(queryMessages, channelId, userId).Iter().Scanner()
+		for scanner.Next() {
+			message := models.Message{UserId: userId}
+			if err := scanner.Scan(&message.ChannelId, &message.CreatedAt, &message.MessageId, &message.Content, &message.AttachmentIds, &message.ServerId); err != nil {
+				return nil, err
+			}
+			message.Attachments = getMessageAttachments(message.AttachmentIds)
+			messages = append(messages, message)
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return messages, nil
+}
+
+func notifyDataExport(export models.DataExport) {
+	url, err := storage.Store.PresignGet(export.ObjectKey, exportLinkDuration)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	data, err := proto.Marshal(&protobuf.ServerMessage{
+		Type: "data_export_ready",
+		Payload: &protobuf.ServerMessage_DataExport{
+			DataExport: &protobuf.DataExport{
+				Id:        export.Id.String(),
+				Url:       url,
+				ExpiresAt: timestamppb.New(time.Now().Add(exportLinkDuration)),
+			},
+		},
+	})
+	if err != nil {
+		fmt.Println("Error when transforming the message into protobuf", err)
+		return
+	}
+
+	sendToUser(export.UserId, data, nil)
+}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// maxGroupDMParticipants caps the size of a group DM, owner included.
+const maxGroupDMParticipants = 10
+
+func CreateGroupDM(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Name    string   `json:"name"`
+		Icon    string   `json:"icon"`
+		UserIds []string `json:"user_ids"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the group informations"})
+	}
+
+	ownerId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the group"})
+	}
+
+	members := []gocql.UUID{ownerId}
+	for _, id := range body.UserIds {
+		userId, err := gocql.ParseUUID(id)
+		if err != nil {
+			return c.Status(422).JSON(fiber.Map{"error": "Invalid user"})
+		}
+
+		if containsUUID(members, userId) {
+			continue
+		}
+
+		if !areFriends(db, ownerId, userId) {
+			return c.Status(403).JSON(fiber.Map{"error": "You can only add your friends to a group"})
+		}
+
+		members = append(members, userId)
+	}
+
+	if len(members) < 2 {
+		return c.Status(422).JSON(fiber.Map{"error": "A group needs at least one other participant"})
+	}
+
+	if len(members) > maxGroupDMParticipants {
+		return c.Status(422).JSON(fiber.Map{"error": "Too many participants in this group"})
+	}
+
+	channelId := utils.GenerateNanoid()
+
+	queryCreateGroup := "INSERT INTO group_dms (channel_id, name, icon, owner, created_at) VALUES (?, ?, ?, ?, ?)"
+	if err := db.Query(queryCreateGroup, channelId, body.Name, body.Icon, ownerId, time.Now()).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the group"})
+	}
+
+	for _, userId := range members {
+		if err := addGroupDMParticipant(db, channelId, userId); err != nil {
+			log.Error(err)
+			return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the group"})
+		}
+	}
+
+	group, err := getGroupDM(channelId)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the group"})
+	}
+
+	broadcastServerChanges(members, Options{DirectMessage: &group}, "group_dm_update")
+
+	return c.JSON(group)
+}
+
+func AddGroupDMParticipant(c *fiber.Ctx) error {
+	db := database.DB
+	channelId := c.Params("channelId")
+	type BodyRequest struct {
+		UserId string `json:"user_id"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the participant"})
+	}
+
+	group, err := getGroupDM(channelId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "This group doesn't exist"})
+	}
+
+	if group.Owner.String() != c.Locals("user_id").(string) {
+		return c.Status(403).JSON(fiber.Map{"error": "Only the owner can add participants"})
+	}
+
+	userId, err := gocql.ParseUUID(body.UserId)
+	if err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	members := getAllUsersFromChannel(channelId, db)
+	if containsUUID(members, userId) {
+		return c.JSON(group)
+	}
+
+	if len(members) >= maxGroupDMParticipants {
+		return c.Status(422).JSON(fiber.Map{"error": "This group is full"})
+	}
+
+	if !areFriends(db, group.Owner, userId) {
+		return c.Status(403).JSON(fiber.Map{"error": "You can only add your friends to a group"})
+	}
+
+	if err := addGroupDMParticipant(db, channelId, userId); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't add the participant"})
+	}
+
+	group, err = getGroupDM(channelId)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't add the participant"})
+	}
+
+	broadcastServerChanges(append(members, userId), Options{DirectMessage: &group}, "group_dm_update")
+
+	return c.JSON(group)
+}
+
+func RemoveGroupDMParticipant(c *fiber.Ctx) error {
+	db := database.DB
+	channelId := c.Params("channelId")
+	type BodyRequest struct {
+		UserId string `json:"user_id"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the participant"})
+	}
+
+	group, err := getGroupDM(channelId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "This group doesn't exist"})
+	}
+
+	if group.Owner.String() != c.Locals("user_id").(string) {
+		return c.Status(403).JSON(fiber.Map{"error": "Only the owner can remove participants"})
+	}
+
+	userId, err := gocql.ParseUUID(body.UserId)
+	if err != nil || userId == group.Owner {
+		return c.Status(422).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	if !containsUUID(getAllUsersFromChannel(channelId, db), userId) {
+		return c.Status(404).JSON(fiber.Map{"error": "This user is not in the group"})
+	}
+
+	if err := removeGroupDMParticipant(db, channelId, userId); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't remove the participant"})
+	}
+
+	group, err = getGroupDM(channelId)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't remove the participant"})
+	}
+
+	broadcastServerChanges([]gocql.UUID{userId}, Options{ChannelId: &channelId}, "group_dm_removal")
+	broadcastServerChanges(getAllUsersFromChannel(channelId, db), Options{DirectMessage: &group}, "group_dm_update")
+
+	return c.JSON(group)
+}
+
+func LeaveGroupDM(c *fiber.Ctx) error {
+	db := database.DB
+	channelId := c.Params("channelId")
+
+	group, err := getGroupDM(channelId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "This group doesn't exist"})
+	}
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't leave the group"})
+	}
+
+	if !containsUUID(getAllUsersFromChannel(channelId, db), userId) {
+		return c.Status(404).JSON(fiber.Map{"error": "You are not in this group"})
+	}
+
+	if err := leaveGroupDM(channelId, group, userId); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't leave the group"})
+	}
+
+	return nil
+}
+
+// leaveGroupDM removes the user from the group, which is deleted once nobody
+// is left in it. The ownership goes to another participant when the owner
+// leaves.
+func leaveGroupDM(channelId string, group models.DirectMessage, userId gocql.UUID) error {
+	db := database.DB
+
+	if err := removeGroupDMParticipant(db, channelId, userId); err != nil {
+		return err
+	}
+
+	broadcastServerChanges([]gocql.UUID{userId}, Options{ChannelId: &channelId}, "group_dm_removal")
+
+	remaining := getAllUsersFromChannel(channelId, db)
+	if len(remaining) == 0 {
+		queryDeleteGroup := "DELETE FROM group_dms WHERE channel_id = ?"
+		if err := db.Query(queryDeleteGroup, channelId).Exec(); err != nil {
+			log.Error(err)
+		}
+
+		queryDeleteMessages := "DELETE FROM messages WHERE channel_id = ?"
+		if err := db.Query(queryDeleteMessages, channelId).Exec(); err != nil {
+			log.Error(err)
+		}
+
+		return nil
+	}
+
+	// The group keeps living without its owner, the ownership goes to
+	// whoever is left first in the participants list.
+	if group.Owner == userId {
+		queryTransferOwnership := "UPDATE group_dms SET owner = ? WHERE channel_id = ?"
+		if err := db.Query(queryTransferOwnership, remaining[0], channelId).Exec(); err != nil {
+			return err
+		}
+	}
+
+	group, err := getGroupDM(channelId)
+	if err != nil {
+		return nil
+	}
+
+	broadcastServerChanges(remaining, Options{DirectMessage: &group}, "group_dm_update")
+
+	return nil
+}
+
+func UpdateGroupDM(c *fiber.Ctx) error {
+	db := database.DB
+	channelId := c.Params("channelId")
+	type BodyRequest struct {
+		Name string `json:"name"`
+		Icon string `json:"icon"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the group informations"})
+	}
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the group"})
+	}
+
+	members := getAllUsersFromChannel(channelId, db)
+	if !isGroupDM(db, channelId) || !containsUUID(members, userId) {
+		return c.Status(404).JSON(fiber.Map{"error": "This group doesn't exist"})
+	}
+
+	queryUpdateGroup := "UPDATE group_dms SET name = ?, icon = ? WHERE channel_id = ?"
+	if err := db.Query(queryUpdateGroup, body.Name, body.Icon, channelId).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the group"})
+	}
+
+	group, err := getGroupDM(channelId)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the group"})
+	}
+
+	broadcastServerChanges(members, Options{DirectMessage: &group}, "group_dm_update")
+
+	return c.JSON(group)
+}
+
+func getGroupDM(channelId string) (models.DirectMessage, error) {
+	db := database.DB
+	group := models.DirectMessage{ChannelId: channelId, Type: dmTypeGroup, Users: []models.User{}}
+
+	queryGetGroup := "SELECT name, icon, owner FROM group_dms WHERE channel_id = ?"
+	if err := db.Query(queryGetGroup, channelId).Scan(&group.Name, &group.Icon, &group.Owner); err != nil {
+		log.Error(err)
+		return group, err
+	}
+
+	for _, userId := range getAllUsersFromChannel(channelId, db) {
+		user, err := GetUserById(userId)
+		if err != nil {
+			continue
+		}
+		group.Users = append(group.Users, user)
+	}
+
+	return group, nil
+}
+
+func isGroupDM(db *gocql.Session, channelId string) bool {
+	var owner gocql.UUID
+	queryGetGroup := "SELECT owner FROM group_dms WHERE channel_id = ?"
+	if err := db.Query(queryGetGroup, channelId).Scan(&owner); err != nil {
+		return false
+	}
+
+	return true
+}
+
+func addGroupDMParticipant(db *gocql.Session, channelId string, userId gocql.UUID) error {
+	queryJoinChannel := "INSERT INTO channel_to_users (channel_id, user_id) VALUES (?, ?)"
+	if err := db.Query(queryJoinChannel, channelId, userId).Exec(); err != nil {
+		return err
+	}
+
+	queryAddToList := "INSERT INTO user_to_dms (user_id, channel_id, last_activity, type) VALUES (?, ?, ?, ?)"
+	return db.Query(queryAddToList, userId, channelId, time.Now(), dmTypeGroup).Exec()
+}
+
+func removeGroupDMParticipant(db *gocql.Session, channelId string, userId gocql.UUID) error {
+	queryLeaveChannel := "DELETE FROM channel_to_users WHERE channel_id = ? AND user_id = ?"
+	if err := db.Query(queryLeaveChannel, channelId, userId).Exec(); err != nil {
+		return err
+	}
+
+	queryRemoveFromList := "DELETE FROM user_to_dms WHERE user_id = ? AND channel_id = ?"
+	return db.Query(queryRemoveFromList, userId, channelId).Exec()
+}
+
+func containsUUID(ids []gocql.UUID, id gocql.UUID) bool {
+	for _, current := range ids {
+		if current == id {
+			return true
+		}
+	}
+
+	return false
+}
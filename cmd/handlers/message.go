@@ -6,9 +6,9 @@ import (
 
 	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
 	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
 	"github.com/gocql/gocql"
-	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 	"google.golang.org/protobuf/proto"
@@ -17,8 +17,6 @@ import (
 
 func NewMessage(c *fiber.Ctx) error {
 	var message models.Message
-	db := database.DB
-	var users []gocql.UUID
 
 	err := c.BodyParser(&message)
 	if err != nil {
@@ -26,32 +24,29 @@ func NewMessage(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Error when sending the message"})
 	}
 
-	channelId := c.Params("channelId")
-	serverId := c.Params("serverId")
-
-	message.ChannelId = channelId
-	message.ServerId = serverId
-	users = getAllUsersFromChannel(message.ChannelId, db)
+	// The sender is whoever is authenticated, never what the body claims.
+	user, err := GetUserById(c.Locals("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	message.User = user
 
-	message.MessageId = gocql.MustRandomUUID()
-	t := time.Now()
-	timestamp := timestamppb.New(t)
-	message.CreatedAt = t
+	message.ChannelId = c.Params("channelId")
+	message.ServerId = c.Params("serverId")
 
-	q := db.Query("INSERT INTO messages (message_id, channel_id, content, mentions, mentions_roles, created_at, sender_id, server_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", message.MessageId, message.ChannelId, message.Content, message.Mentions, message.MentionsRoles, message.CreatedAt, message.User.Id, message.ServerId)
-	if err := q.Exec(); err != nil {
-		log.Errorf("Error when creating the user: %v", err)
+	if limited, err := limitMessage(c, message.ChannelId, message.Content); limited {
+		return err
 	}
 
-	broadcastMessage(message.User, users, message, timestamp)
+	if status, err := sendChannelMessage(&message); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	return nil
 }
 
 func NewDM(c *fiber.Ctx) error {
 	var message models.Message
-	db := database.DB
-	var users []gocql.UUID
 
 	err := c.BodyParser(&message)
 	if err != nil {
@@ -59,9 +54,59 @@ func NewDM(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Error when sending the message"})
 	}
 
-	channelId := c.Params("channelId")
+	user, err := GetUserById(c.Locals("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	message.User = user
+
+	message.ChannelId = c.Params("channelId")
+
+	if limited, err := limitMessage(c, message.ChannelId, message.Content); limited {
+		return err
+	}
+
+	if status, err := sendDirectMessage(&message); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return nil
+}
+
+// sendChannelMessage stores and broadcasts a message posted in a server
+// channel, whether it comes from NewMessage or from the scheduler.
+func sendChannelMessage(message *models.Message) (int, error) {
+	db := database.DB
+
+	// The server comes from the client, the channel has to be one of its own.
+	if !isServerChannel(db, message.ServerId, message.ChannelId) {
+		return fiber.StatusNotFound, fmt.Errorf("Channel not found")
+	}
+
+	users := getAllUsersFromChannel(message.ChannelId, db)
+	if !containsUUID(users, message.User.Id) {
+		return fiber.StatusForbidden, fmt.Errorf("You are not in this channel")
+	}
+
+	resolveMentions(message, users)
+
+	return storeMessage(db, message, users)
+}
+
+// sendDirectMessage is the same for a DM or a group DM, the sender must be
+// one of its participants.
+func sendDirectMessage(message *models.Message) (int, error) {
+	db := database.DB
+	var users []gocql.UUID
+	message.ServerId = ""
+
+	// Members of a group DM don't have to be friends with each other, being
+	// in the group is enough to talk to everyone in it.
+	isGroup := isGroupDM(db, message.ChannelId)
+	isMember := false
+
 	querySub := "SELECT user_id FROM channel_to_users WHERE channel_id = ?"
-	scanner := db.Query(querySub, channelId).Iter().Scanner()
+	scanner := db.Query(querySub, message.ChannelId).Iter().Scanner()
 	for scanner.Next() {
 		var user_id gocql.UUID
 		err := scanner.Scan(&user_id)
@@ -71,30 +116,102 @@ func NewDM(c *fiber.Ctx) error {
 
 		if user_id != message.User.Id {
 			users = append(users, user_id)
-			err := isFriend(c, db, message.User.Id, user_id)
-			if err != nil {
-				return err
+			if !isGroup && !areFriends(db, message.User.Id, user_id) {
+				return fiber.StatusInternalServerError, fmt.Errorf("The two users are not friends")
 			}
+		} else {
+			isMember = true
 		}
 	}
 
+	if !isMember {
+		return fiber.StatusForbidden, fmt.Errorf("You are not in this conversation")
+	}
+
 	users = append(users, message.User.Id)
+	resolveMentions(message, users)
 
-	message.ChannelId = channelId
+	status, err := storeMessage(db, message, users)
+	if err != nil {
+		return status, err
+	}
+
+	touchDM(db, users, message.ChannelId, message.CreatedAt)
+
+	return status, nil
+}
+
+func isServerChannel(db *gocql.Session, serverId string, channelId string) bool {
+	if serverId == "" {
+		return false
+	}
 
+	var id string
+	queryGetChannel := "SELECT channel_id FROM channels WHERE server_id = ? AND channel_id = ?"
+	if err := db.Query(queryGetChannel, serverId, channelId).Scan(&id); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// getChannelServer returns the server of a channel the user is in, "" for
+// one of their DMs or group DMs. The channels are stored by server, so the
+// server the client names is only checked, never trusted.
+func getChannelServer(db *gocql.Session, userId gocql.UUID, channelId string, serverId string) (string, error) {
+	var id string
+	queryGetDM := "SELECT channel_id FROM user_to_dms WHERE user_id = ? AND channel_id = ?"
+	if err := db.Query(queryGetDM, userId, channelId).Scan(&id); err == nil {
+		return "", nil
+	} else if err != gocql.ErrNotFound {
+		return "", err
+	}
+
+	if !isServerChannel(db, serverId, channelId) {
+		return "", gocql.ErrNotFound
+	}
+
+	return serverId, nil
+}
+
+func storeMessage(db *gocql.Session, message *models.Message, users []gocql.UUID) (int, error) {
 	message.MessageId = gocql.MustRandomUUID()
 	t := time.Now()
 	timestamp := timestamppb.New(t)
 	message.CreatedAt = t
 
-	q := db.Query("INSERT INTO messages (message_id, channel_id, content, mentions, mentions_roles, created_at, sender_id, server_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", message.MessageId, message.ChannelId, message.Content, message.Mentions, message.MentionsRoles, message.CreatedAt, message.User.Id, message.ServerId)
+	// Where the user wrote, for the deletion and the export of their account
+	// to find every message, even in channels they left. It's recorded
+	// before the message so none can be missed.
+	if err := db.Query("INSERT INTO user_to_message_channels (user_id, channel_id) VALUES (?, ?)", message.User.Id, message.ChannelId).Exec(); err != nil {
+		log.Error(err)
+		return fiber.StatusInternalServerError, fmt.Errorf("Couldn't send the message")
+	}
+
+	if err := linkAttachments(db, message); err != nil {
+		log.Error(err)
+		return fiber.StatusUnprocessableEntity, fmt.Errorf("Invalid attachments")
+	}
+
+	q := db.Query("INSERT INTO messages (message_id, channel_id, content, mentions, mentions_roles, mention_everyone, attachments, created_at, sender_id, server_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", message.MessageId, message.ChannelId, message.Content, message.Mentions, message.MentionsRoles, message.MentionEveryone, message.AttachmentIds, message.CreatedAt, message.User.Id, message.ServerId)
 	if err := q.Exec(); err != nil {
-		log.Errorf("Error when creating the user: %v", err)
+		log.Errorf("Error when creating the message: %v", err)
+		// The attachments can be sent again with another message.
+		unlinkAttachments(db, message.MessageId, message.Attachments)
+		return fiber.StatusInternalServerError, fmt.Errorf("Couldn't send the message")
 	}
 
-	broadcastMessage(message.User, users, message, timestamp)
+	if err := setReadState(message.User.Id, message.ServerId, message.ChannelId, message.MessageId, message.CreatedAt, nil); err != nil {
+		log.Errorf("Error when updating the read state: %v", err)
+	}
 
-	return nil
+	broadcastMessage(message.User, users, *message, timestamp)
+	queueUnfurl(*message)
+
+	return fiber.StatusOK, nil
 }
 
 func getAllUsersFromChannel(channelId string, db *gocql.Session) []gocql.UUID {
@@ -115,7 +232,30 @@ func getAllUsersFromChannel(channelId string, db *gocql.Session) []gocql.UUID {
 }
 
 func broadcastMessage(sender models.User, users []gocql.UUID, message models.Message, timestamp *timestamppb.Timestamp) {
+	userMessage := userMessageToProtobuf(sender, message, timestamp)
+
+	messageToSend := &protobuf.ServerMessage{
+		Type: "message",
+		Payload: &protobuf.ServerMessage_UserMessage{
+			UserMessage: userMessage,
+		},
+	}
+
+	data, err := proto.Marshal(messageToSend)
+	if err != nil {
+		fmt.Println("Error when transforming the message into protobuf", err)
+	}
+
+	for _, user_id := range users {
+		sendToUser(user_id, data, nil)
+	}
 
+	// The notifications depend on the settings of every member, they're sent
+	// after the request is answered.
+	go notifyMessage(sender, users, message, userMessage)
+}
+
+func userMessageToProtobuf(sender models.User, message models.Message, timestamp *timestamppb.Timestamp) *protobuf.UserMessage {
 	messageSender := &protobuf.User{
 		Id:          sender.Id.String(),
 		Email:       sender.Email,
@@ -131,31 +271,44 @@ func broadcastMessage(sender models.User, users []gocql.UUID, message models.Mes
 		}
 	}
 
-	messageToSend := &protobuf.ServerMessage{
-		Type: "message",
-		Payload: &protobuf.ServerMessage_UserMessage{
-			UserMessage: &protobuf.UserMessage{
-				Id:            message.MessageId.String(),
-				Content:       message.Content,
-				Mentions:      mentions,
-				MentionsRoles: message.MentionsRoles,
-				ChannelId:     message.ChannelId,
-				CreatedAt:     timestamp,
-				Sender:        messageSender,
-			},
-		},
+	var attachments []*protobuf.Attachment
+	for _, attachment := range message.Attachments {
+		attachments = append(attachments, attachmentToProtobuf(attachment))
 	}
 
-	data, err := proto.Marshal(messageToSend)
-	if err != nil {
-		fmt.Println("Error when transforming the message into protobuf", err)
+	var embeds []*protobuf.Embed
+	for _, embed := range message.Embeds {
+		embeds = append(embeds, embedToProtobuf(embed))
 	}
 
-	for _, user_id := range users {
-		if conn := Connections[user_id]; conn != nil {
-			conn.WriteMessage(websocket.BinaryMessage, data)
+	return &protobuf.UserMessage{
+		Id:              message.MessageId.String(),
+		Content:         message.Content,
+		Mentions:        mentions,
+		MentionsRoles:   message.MentionsRoles,
+		MentionEveryone: message.MentionEveryone,
+		ChannelId:       message.ChannelId,
+		CreatedAt:       timestamp,
+		Sender:          messageSender,
+		Attachments:     attachments,
+		Embeds:          embeds,
+	}
+}
+
+// resolveMentions replaces whatever mentions the client sent by the ones
+// found in the content, only keeping users able to see the channel.
+func resolveMentions(message *models.Message, members []gocql.UUID) {
+	mentionedUsers, mentionedRoles, everyone := utils.ParseMentions(message.Content)
+
+	message.Mentions = nil
+	for _, userId := range mentionedUsers {
+		if containsUUID(members, userId) {
+			message.Mentions = append(message.Mentions, userId)
 		}
 	}
+
+	message.MentionsRoles = mentionedRoles
+	message.MentionEveryone = everyone
 }
 
 func GetMessageFromChannel(c *fiber.Ctx) error {
@@ -164,37 +317,43 @@ func GetMessageFromChannel(c *fiber.Ctx) error {
 
 	channelId := c.Params("channelId")
 
-	queryMessage := "SELECT * FROM messages WHERE channel_id = ?"
-	queryUser := "SELECT * FROM users WHERE id = ?"
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the messages"})
+	}
+
+	if !containsUUID(getAllUsersFromChannel(channelId, db), userId) {
+		return c.Status(403).JSON(fiber.Map{"error": "You are not in this channel"})
+	}
+
+	queryMessage := "SELECT channel_id, created_at, message_id, content, mentions, mentions_roles, mention_everyone, attachments, sender_id, server_id FROM messages WHERE channel_id = ?"
+	queryUser := "SELECT id, about, avatar, banner, bot, displayname, email, password, username FROM users WHERE id = ?"
 
 	scanner := db.Query(queryMessage, channelId).Iter().Scanner()
 	for scanner.Next() {
 		var message models.Message
 		var user models.User
 
-		err := scanner.Scan(&message.ChannelId, &message.CreatedAt, &message.MessageId, &message.Content, &message.Mentions, &message.MentionsRoles, &message.UserId, &message.ServerId)
+		err := scanner.Scan(&message.ChannelId, &message.CreatedAt, &message.MessageId, &message.Content, &message.Mentions, &message.MentionsRoles, &message.MentionEveryone, &message.AttachmentIds, &message.UserId, &message.ServerId)
 		if err != nil {
 			log.Error(err)
 		}
 
-		if err := db.Query(queryUser, message.UserId).Scan(&user.Id, &user.About, &user.Avatar, &user.Banner, &user.DisplayName, &user.Email, &user.Password, &user.Username); err != nil {
+		if err := db.Query(queryUser, message.UserId).Scan(&user.Id, &user.About, &user.Avatar, &user.Banner, &user.Bot, &user.DisplayName, &user.Email, &user.Password, &user.Username); err == gocql.ErrNotFound {
+			// Deleted, and not anonymized as the user had left the channel
+			// before their messages were recorded.
+			user = deletedUser()
+		} else if err != nil {
 			log.Error(err)
 			return c.Status(404).JSON(fiber.Map{"error": "Error when fetching matching user of message"})
 		}
 
 		message.User = user
+		message.Attachments = getMessageAttachments(message.AttachmentIds)
+		message.Embeds = getMessageEmbeds(message.MessageId)
 		messages = append(messages, message)
 	}
 
 	return c.JSON(messages)
 }
-
-func isFriend(c *fiber.Ctx, db *gocql.Session, userId interface{}, friendId gocql.UUID) error {
-	queryCheckFriend := db.Query("SELECT friend_id FROM friends WHERE user_id = ? AND friend_id = ?", userId, friendId)
-	if err := queryCheckFriend.Scan(&friendId); err != nil {
-		log.Error(err)
-		return c.Status(500).JSON(fiber.Map{"error": "The two users are not friends"})
-	}
-
-	return nil
-}
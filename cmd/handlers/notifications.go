@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	notificationLevelAll      = "all"
+	notificationLevelMentions = "mentions"
+	notificationLevelNothing  = "nothing"
+)
+
+// notificationPolicy is what applies to a user in a given channel once the
+// channel settings have been merged over the server ones.
+type notificationPolicy struct {
+	Level            string
+	Muted            bool
+	SuppressEveryone bool
+}
+
+func (policy notificationPolicy) allowsMention(direct bool) bool {
+	if policy.Muted || policy.Level == notificationLevelNothing {
+		return false
+	}
+
+	return direct || !policy.SuppressEveryone
+}
+
+func (policy notificationPolicy) allowsMessage() bool {
+	return !policy.Muted && policy.Level == notificationLevelAll
+}
+
+func GetNotificationSettings(c *fiber.Ctx) error {
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch your notification settings"})
+	}
+
+	settings, err := getNotificationSettings(userId)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch your notification settings"})
+	}
+
+	return c.JSON(settings)
+}
+
+func UpdateNotificationSettings(c *fiber.Ctx) error {
+	db := database.DB
+	var settings models.NotificationSettings
+
+	err := c.BodyParser(&settings)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the notification settings"})
+	}
+
+	if settings.TargetId == "" || (settings.TargetType != "server" && settings.TargetType != "channel") {
+		return c.Status(422).JSON(fiber.Map{"error": "Invalid notification target"})
+	}
+
+	switch settings.Level {
+	case "", notificationLevelAll, notificationLevelMentions, notificationLevelNothing:
+	default:
+		return c.Status(422).JSON(fiber.Map{"error": "Invalid notification level"})
+	}
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't update your notification settings"})
+	}
+
+	queryUpdateSettings := "INSERT INTO notification_settings (user_id, target_id, target_type, level, muted_until, suppress_everyone) VALUES (?, ?, ?, ?, ?, ?)"
+	if err := db.Query(queryUpdateSettings, userId, settings.TargetId, settings.TargetType, settings.Level, settings.MutedUntil, settings.SuppressEveryone).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't update your notification settings"})
+	}
+
+	return c.JSON(settings)
+}
+
+func ResetNotificationSettings(c *fiber.Ctx) error {
+	db := database.DB
+	targetId := c.Params("targetId")
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't reset your notification settings"})
+	}
+
+	queryDeleteSettings := "DELETE FROM notification_settings WHERE user_id = ? AND target_id = ?"
+	if err := db.Query(queryDeleteSettings, userId, targetId).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't reset your notification settings"})
+	}
+
+	return nil
+}
+
+func getNotificationSettings(userId gocql.UUID) ([]models.NotificationSettings, error) {
+	db := database.DB
+	settings := []models.NotificationSettings{}
+
+	querySettings := "SELECT target_id, target_type, level, muted_until, suppress_everyone FROM notification_settings WHERE user_id = ?"
+	scanner := db.Query(querySettings, userId).Iter().Scanner()
+	for scanner.Next() {
+		var setting models.NotificationSettings
+		if err := scanner.Scan(&setting.TargetId, &setting.TargetType, &setting.Level, &setting.MutedUntil, &setting.SuppressEveryone); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		settings = append(settings, setting)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// getNotificationPolicy merges the channel settings of the user over the
+// server ones.
+func getNotificationPolicy(userId gocql.UUID, serverId string, channelId string) notificationPolicy {
+	db := database.DB
+	settings := map[string]models.NotificationSettings{}
+
+	querySettings := "SELECT level, muted_until, suppress_everyone FROM notification_settings WHERE user_id = ? AND target_id = ?"
+	for _, targetId := range []string{serverId, channelId} {
+		if targetId == "" {
+			continue
+		}
+
+		setting := models.NotificationSettings{TargetId: targetId}
+		if err := db.Query(querySettings, userId, targetId).Scan(&setting.Level, &setting.MutedUntil, &setting.SuppressEveryone); err != nil {
+			if err != gocql.ErrNotFound {
+				log.Error(err)
+			}
+			continue
+		}
+		settings[targetId] = setting
+	}
+
+	return mergeNotificationSettings(settings, serverId, channelId)
+}
+
+// mergeNotificationSettings picks the policy out of the settings of a user,
+// keyed by target. A channel row replaces the server one, only an empty level
+// is taken from the server. Without any setting, DMs notify for every message
+// while server channels only notify for mentions.
+func mergeNotificationSettings(settings map[string]models.NotificationSettings, serverId string, channelId string) notificationPolicy {
+	policy := notificationPolicy{Level: notificationLevelMentions}
+	if serverId == "" {
+		policy.Level = notificationLevelAll
+	}
+
+	now := time.Now()
+	for _, targetId := range []string{serverId, channelId} {
+		setting, ok := settings[targetId]
+		if targetId == "" || !ok {
+			continue
+		}
+
+		if setting.Level != "" {
+			policy.Level = setting.Level
+		}
+		policy.Muted = setting.MutedUntil.After(now)
+		policy.SuppressEveryone = setting.SuppressEveryone
+	}
+
+	return policy
+}
+
+// Scylla refuses an IN over more partition keys than this.
+const maxSettingsBatch = 100
+
+// getMembersNotificationSettings fetches the settings the members have on the
+// server and the channel, keyed by user then by target, maxSettingsBatch
+// members per query. A batch that fails is read member by member, and the
+// members whose settings still couldn't be read are left out of the result
+// and returned apart.
+func getMembersNotificationSettings(members []gocql.UUID, serverId string, channelId string) (map[gocql.UUID]map[string]models.NotificationSettings, []gocql.UUID) {
+	settings := map[gocql.UUID]map[string]models.NotificationSettings{}
+	var unknown []gocql.UUID
+
+	targets := []string{channelId}
+	if serverId != "" {
+		targets = append(targets, serverId)
+	}
+
+	for start := 0; start < len(members); start += maxSettingsBatch {
+		end := start + maxSettingsBatch
+		if end > len(members) {
+			end = len(members)
+		}
+
+		batch := members[start:end]
+		err := readNotificationSettings(settings, batch, targets)
+		if err == nil {
+			continue
+		}
+		log.Errorf("Error when fetching the notification settings of %d members: %v", len(batch), err)
+
+		for _, userId := range batch {
+			if err := readNotificationSettings(settings, []gocql.UUID{userId}, targets); err != nil {
+				log.Errorf("Error when fetching the notification settings of %s: %v", userId, err)
+				delete(settings, userId)
+				unknown = append(unknown, userId)
+			}
+		}
+	}
+
+	return settings, unknown
+}
+
+func readNotificationSettings(settings map[gocql.UUID]map[string]models.NotificationSettings, members []gocql.UUID, targets []string) error {
+	db := database.DB
+
+	querySettings := "SELECT user_id, target_id, level, muted_until, suppress_everyone FROM notification_settings WHERE user_id IN ? AND target_id IN ?"
+	scanner := db.Query(querySettings, members, targets).Iter().Scanner()
+	for scanner.Next() {
+		var userId gocql.UUID
+		var setting models.NotificationSettings
+		if err := scanner.Scan(&userId, &setting.TargetId, &setting.Level, &setting.MutedUntil, &setting.SuppressEveryone); err != nil {
+			return err
+		}
+
+		if settings[userId] == nil {
+			settings[userId] = map[string]models.NotificationSettings{}
+		}
+		settings[userId][setting.TargetId] = setting
+	}
+
+	return scanner.Err()
+}
+
+// notifyMessage tells the members of a channel about a new message according
+// to their notification settings: a "mention" when they're mentioned and
+// accept it, a plain "notification" when they want every message.
+func notifyMessage(sender models.User, members []gocql.UUID, message models.Message, userMessage *protobuf.UserMessage) {
+	notification := &protobuf.MentionNotification{
+		ServerId:  message.ServerId,
+		ChannelId: message.ChannelId,
+		Message:   userMessage,
+	}
+
+	// Without their settings, the members may have muted the channel.
+	settings, unknown := getMembersNotificationSettings(members, message.ServerId, message.ChannelId)
+
+	for _, userId := range members {
+		if userId == sender.Id || containsUUID(unknown, userId) {
+			continue
+		}
+
+		direct := containsUUID(message.Mentions, userId)
+		policy := mergeNotificationSettings(settings[userId], message.ServerId, message.ChannelId)
+
+		typeOfMessage := ""
+		if (direct || message.MentionEveryone) && policy.allowsMention(direct) {
+			typeOfMessage = "mention"
+		} else if policy.allowsMessage() {
+			typeOfMessage = "notification"
+		} else {
+			continue
+		}
+
+		data, err := proto.Marshal(&protobuf.ServerMessage{
+			Type: typeOfMessage,
+			Payload: &protobuf.ServerMessage_MentionNotification{
+				MentionNotification: notification,
+			},
+		})
+		if err != nil {
+			fmt.Println("Error when transforming the message into protobuf", err)
+			return
+		}
+
+		sendToUser(userId, data, nil)
+	}
+}
+
+func notificationSettingsToProtobuf(settings models.NotificationSettings) *protobuf.NotificationSettings {
+	return &protobuf.NotificationSettings{
+		TargetId:         settings.TargetId,
+		TargetType:       settings.TargetType,
+		Level:            settings.Level,
+		MutedUntil:       timestamppb.New(settings.MutedUntil),
+		SuppressEveryone: settings.SuppressEveryone,
+	}
+}
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/auth"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/oidc"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// The state of a login started with a provider, it has to come back before
+// oidcStateDuration. It's also kept in a cookie, so the callback is only
+// accepted in the browser which started the login: otherwise anyone could
+// send their own callback link to someone and log them in their account.
+const (
+	oidcStateDuration = 10 * time.Minute
+	oidcStateCookie   = "oidc_state"
+	oidcCookiePath    = "/api/user/oidc"
+
+	// How long a login through the provider stands in for the password of
+	// an account which has none.
+	oidcReauthDuration = 5 * time.Minute
+
+	oidcLinkAccount   = "link"
+	oidcCreateAccount = "create"
+)
+
+var (
+	errOIDCNoVerifiedEmail = errors.New("the provider didn't give a verified email")
+	errOIDCEmailNotLinked  = errors.New("an account with this email exists but its email isn't verified")
+
+	usernameInvalidCharacters = regexp.MustCompile(`[^a-zA-Z0-9_.]`)
+)
+
+// GetOIDCProviders lists the providers the user can log in with.
+func GetOIDCProviders(c *fiber.Ctx) error {
+	providers := []string{}
+	for name := range oidc.Providers {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+
+	return c.JSON(providers)
+}
+
+// OIDCAuthorize sends the user to the provider, the timezone and user agent
+// of the session to create are given as query parameters. With reauth=true
+// a logged in user confirms it's them instead, which is how an account
+// without password gets through checkCurrentPassword.
+func OIDCAuthorize(c *fiber.Ctx) error {
+	db := database.DB
+
+	provider, err := oidc.Get(c.Params("provider"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Unknown provider"})
+	}
+
+	var reauthUserId interface{}
+	if c.Query("reauth") == "true" {
+		identity, err := auth.Sessions.Authenticate(c.Cookies("session"))
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		reauthUserId = identity.UserId
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		if secrets[i], err = randomToken(32); err != nil {
+			log.Error(err)
+			return c.Status(500).JSON(fiber.Map{"error": "Couldn't log in with this provider"})
+		}
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	authURL, err := provider.AuthURL(state, nonce, verifier)
+	if err != nil {
+		log.Error(err)
+		return c.Status(502).JSON(fiber.Map{"error": "The provider is unavailable"})
+	}
+
+	queryState := "INSERT INTO oidc_states (state_hash, provider, nonce, verifier, timezone, user_agent, reauth_user_id) VALUES (?, ?, ?, ?, ?, ?, ?) USING TTL ?"
+	if err := db.Query(queryState, hashToken(state), c.Params("provider"), nonce, verifier, c.Query("timezone"), c.Query("user_agent"), reauthUserId, int(oidcStateDuration.Seconds())).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't log in with this provider"})
+	}
+
+	// Lax, as the callback is a top-level navigation coming from the
+	// provider.
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    hashToken(state),
+		Path:     oidcCookiePath,
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Lax",
+		Expires:  time.Now().Add(oidcStateDuration),
+	})
+
+	return c.Redirect(authURL)
+}
+
+// OIDCCallback is where the provider sends the user back. The identity is
+// matched to the account it's linked to, or to the account with the same
+// verified email which it's then linked to, or a new account is created.
+// The user is sent back to the client, logged in or with an error.
+func OIDCCallback(c *fiber.Ctx) error {
+	db := database.DB
+	name := c.Params("provider")
+
+	provider, err := oidc.Get(name)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Unknown provider"})
+	}
+
+	if c.Query("error") != "" {
+		return oidcFailed(c, "cancelled")
+	}
+
+	stateHash := hashToken(c.Query("state"))
+	stateCookie := c.Cookies(oidcStateCookie)
+	clearOIDCStateCookie(c)
+	if subtle.ConstantTimeCompare([]byte(stateCookie), []byte(stateHash)) != 1 {
+		return oidcFailed(c, "expired")
+	}
+
+	var stateProvider, nonce, verifier, timezone, userAgent string
+	var reauthUserId gocql.UUID
+	queryState := "SELECT provider, nonce, verifier, timezone, user_agent, reauth_user_id FROM oidc_states WHERE state_hash = ?"
+	if err := db.Query(queryState, stateHash).Scan(&stateProvider, &nonce, &verifier, &timezone, &userAgent, &reauthUserId); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return oidcFailed(c, "expired")
+	}
+
+	queryUseState := "DELETE FROM oidc_states WHERE state_hash = ? IF EXISTS"
+	applied, err := db.Query(queryUseState, stateHash).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		log.Error(err)
+		return oidcFailed(c, "failed")
+	}
+
+	if !applied || stateProvider != name {
+		return oidcFailed(c, "expired")
+	}
+
+	claims, err := provider.Exchange(c.Query("code"), verifier, nonce)
+	if err != nil {
+		log.Errorf("Error when logging in with %s: %v", name, err)
+		return oidcFailed(c, "failed")
+	}
+
+	if reauthUserId != (gocql.UUID{}) {
+		return oidcReauthenticate(c, name, claims, reauthUserId)
+	}
+
+	user, err := getOIDCUser(name, claims)
+	if err != nil {
+		switch err {
+		case errOIDCNoVerifiedEmail:
+			return oidcFailed(c, "email_not_verified")
+		case errOIDCEmailNotLinked:
+			return oidcFailed(c, "account_exists")
+		}
+		log.Error(err)
+		return oidcFailed(c, "failed")
+	}
+
+	enabled, err := twoFactorEnabled(user.Id)
+	if err != nil {
+		log.Error(err)
+		return oidcFailed(c, "failed")
+	}
+
+	if enabled {
+		challenge, err := createLoginChallenge(user.Id, timezone, userAgent)
+		if err != nil {
+			log.Error(err)
+			return oidcFailed(c, "failed")
+		}
+
+		return c.Redirect(appLink("/login/2fa", challenge))
+	}
+
+	if err := createSession(c, user, timezone, userAgent); err != nil {
+		log.Error(err)
+		return oidcFailed(c, "failed")
+	}
+
+	return c.Redirect(appURL("/"))
+}
+
+// oidcReauthenticate records that the user just proved it's them with an
+// identity linked to their account, for oidcReauthDuration.
+func oidcReauthenticate(c *fiber.Ctx, provider string, claims oidc.Claims, userId gocql.UUID) error {
+	db := database.DB
+
+	var linkedUserId gocql.UUID
+	queryIdentity := "SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?"
+	if err := db.Query(queryIdentity, provider, claims.Subject).Scan(&linkedUserId); err != nil || linkedUserId != userId {
+		if err != nil && err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return c.Redirect(appURL("/settings") + "?oidc_error=wrong_account")
+	}
+
+	queryReauth := "INSERT INTO recent_reauths (user_id, reauthenticated_at) VALUES (?, ?) USING TTL ?"
+	if err := db.Query(queryReauth, userId, time.Now(), int(oidcReauthDuration.Seconds())).Exec(); err != nil {
+		log.Error(err)
+		return c.Redirect(appURL("/settings") + "?oidc_error=failed")
+	}
+
+	return c.Redirect(appURL("/settings") + "?reauth=ok")
+}
+
+func clearOIDCStateCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     oidcCookiePath,
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Lax",
+		Expires:  time.Unix(0, 0),
+	})
+}
+
+func oidcFailed(c *fiber.Ctx, reason string) error {
+	return c.Redirect(appURL("/login") + "?oidc_error=" + url.QueryEscape(reason))
+}
+
+// getOIDCUser returns the account of the identity, linking or creating it
+// the first time.
+func getOIDCUser(provider string, claims oidc.Claims) (models.User, error) {
+	db := database.DB
+
+	var userId gocql.UUID
+	queryIdentity := "SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?"
+	err := db.Query(queryIdentity, provider, claims.Subject).Scan(&userId)
+	if err == nil {
+		return GetUserById(userId)
+	}
+	if err != gocql.ErrNotFound {
+		return models.User{}, err
+	}
+
+	addr, err := mail.ParseAddress(claims.Email)
+	if err != nil {
+		return models.User{}, errOIDCNoVerifiedEmail
+	}
+
+	var existing *models.User
+	if user, err := getUserByEmail(addr.Address); err == nil {
+		existing = &user
+	}
+
+	action, err := oidcAccountAction(claims, existing)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	var user models.User
+	if action == oidcLinkAccount {
+		user = *existing
+	} else if user, err = createOIDCUser(addr.Address, claims.Name); err != nil {
+		return models.User{}, err
+	}
+
+	queryLink := "INSERT INTO user_identities (provider, subject, user_id, email, created_at) VALUES (?, ?, ?, ?, ?) IF NOT EXISTS"
+	applied, err := db.Query(queryLink, provider, claims.Subject, user.Id, addr.Address, time.Now()).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	// The same identity was linked concurrently, to this account or to the
+	// one created by the other callback.
+	if !applied {
+		if err := db.Query(queryIdentity, provider, claims.Subject).Scan(&userId); err != nil {
+			return models.User{}, err
+		}
+		return GetUserById(userId)
+	}
+
+	queryUserIdentity := "INSERT INTO user_to_identities (user_id, provider, subject) VALUES (?, ?, ?)"
+	if err := db.Query(queryUserIdentity, user.Id, provider, claims.Subject).Exec(); err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// oidcAccountAction decides what a new identity leads to, given the account
+// already using its email if there's one: it's linked to it or a new
+// account is created.
+func oidcAccountAction(claims oidc.Claims, existing *models.User) (string, error) {
+	if _, err := mail.ParseAddress(claims.Email); err != nil || !claims.EmailVerified {
+		return "", errOIDCNoVerifiedEmail
+	}
+
+	if existing == nil {
+		return oidcCreateAccount, nil
+	}
+
+	// Anyone could have signed up with an address they don't own, so the
+	// account is only taken over once its email was verified.
+	if !existing.EmailVerified {
+		return "", errOIDCEmailNotLinked
+	}
+
+	return oidcLinkAccount, nil
+}
+
+// createOIDCUser creates an account without password, the sensitive changes
+// ask to log in through the provider again instead, or one can be set with a
+// password reset. The username comes from the email, with a number added
+// until it's free.
+func createOIDCUser(email string, name string) (models.User, error) {
+	db := database.DB
+
+	user := models.User{
+		Id:            gocql.MustRandomUUID(),
+		Email:         email,
+		EmailVerified: true,
+		DisplayName:   strings.TrimSpace(name),
+	}
+	if len([]rune(user.DisplayName)) > maxDisplayNameLength {
+		user.DisplayName = string([]rune(user.DisplayName)[:maxDisplayNameLength])
+	}
+
+	if ok, err := claimUserLookup("existing_email", email, user.Id); err != nil {
+		return user, err
+	} else if !ok {
+		// Taken since getUserByEmail, the account may not be verified.
+		return user, errOIDCEmailNotLinked
+	}
+
+	base := usernameInvalidCharacters.ReplaceAllString(strings.Split(email, "@")[0], "")
+	if len(base) > 24 {
+		base = base[:24]
+	}
+	if len(base) < 2 {
+		base = "user"
+	}
+
+	for attempt := 0; user.Username == ""; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix, err := randomToken(3)
+			if err != nil {
+				releaseUserLookup("existing_email", email, user.Id)
+				return user, err
+			}
+			username = base + "_" + usernameInvalidCharacters.ReplaceAllString(suffix, "")
+		}
+
+		ok, err := claimUserLookup("existing_username", username, user.Id)
+		if err != nil || (!ok && attempt == 5) {
+			releaseUserLookup("existing_email", email, user.Id)
+			if err == nil {
+				err = errors.New("couldn't find a free username")
+			}
+			return user, err
+		}
+
+		if ok {
+			user.Username = username
+		}
+	}
+
+	queryCreateUser := "INSERT INTO users (id, username, displayname, email, email_verified, password) VALUES (?, ?, ?, ?, true, '')"
+	if err := db.Query(queryCreateUser, user.Id, user.Username, user.DisplayName, user.Email).Exec(); err != nil {
+		releaseUserLookup("existing_username", user.Username, user.Id)
+		releaseUserLookup("existing_email", email, user.Id)
+		return user, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/oidc"
+)
+
+func TestOIDCAccountAction(t *testing.T) {
+	verified := oidc.Claims{Subject: "subject-1", Email: "user@example.com", EmailVerified: true}
+	unverified := oidc.Claims{Subject: "subject-1", Email: "user@example.com"}
+
+	tests := []struct {
+		name     string
+		claims   oidc.Claims
+		existing *models.User
+		action   string
+		err      error
+	}{
+		{name: "new email creates an account", claims: verified, action: oidcCreateAccount},
+		{name: "verified email links to the verified account", claims: verified, existing: &models.User{Email: "user@example.com", EmailVerified: true}, action: oidcLinkAccount},
+		{name: "unverified account isn't taken over", claims: verified, existing: &models.User{Email: "user@example.com"}, err: errOIDCEmailNotLinked},
+		{name: "unverified provider email is refused", claims: unverified, existing: &models.User{Email: "user@example.com", EmailVerified: true}, err: errOIDCNoVerifiedEmail},
+		{name: "missing email is refused", claims: oidc.Claims{Subject: "subject-1", EmailVerified: true}, err: errOIDCNoVerifiedEmail},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			action, err := oidcAccountAction(test.claims, test.existing)
+			if err != test.err || action != test.action {
+				t.Fatalf("got (%q, %v), want (%q, %v)", action, err, test.action, test.err)
+			}
+		})
+	}
+}
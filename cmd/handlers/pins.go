@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const maxPinsByChannel = 50
+
+func PinMessage(c *fiber.Ctx) error {
+	db := database.DB
+	channelId := c.Params("channelId")
+
+	userId, serverId, message, status, err := getPinnableMessage(c)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// The place is taken before the pin, and given back when it isn't made.
+	reserved, err := adjustPinCount(db, channelId, 1)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't pin the message"})
+	}
+
+	if !reserved {
+		return c.Status(409).JSON(fiber.Map{"error": fmt.Sprintf("A channel can't have more than %d pinned messages", maxPinsByChannel)})
+	}
+
+	pinnedAt := time.Now()
+	queryPinMessage := "INSERT INTO pinned_messages (channel_id, message_id, pinned_by, pinned_at) VALUES (?, ?, ?, ?) IF NOT EXISTS"
+	applied, err := db.Query(queryPinMessage, channelId, message.MessageId, userId, pinnedAt).MapScanCAS(map[string]interface{}{})
+	if err != nil || !applied {
+		if _, err := adjustPinCount(db, channelId, -1); err != nil {
+			log.Error(err)
+		}
+	}
+
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't pin the message"})
+	}
+
+	if applied {
+		if serverId != "" {
+			recordAudit(serverId, userId.String(), auditActionMessagePin, "message", message.MessageId.String(), nil, map[string]interface{}{"channel_id": channelId})
+		}
+		broadcastPinUpdate(channelId, message, true, userId, pinnedAt)
+	}
+
+	return c.JSON(message)
+}
+
+func UnpinMessage(c *fiber.Ctx) error {
+	db := database.DB
+	channelId := c.Params("channelId")
+
+	userId, serverId, message, status, err := getPinnableMessage(c)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	queryUnpinMessage := "DELETE FROM pinned_messages WHERE channel_id = ? AND message_id = ? IF EXISTS"
+	applied, err := db.Query(queryUnpinMessage, channelId, message.MessageId).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't unpin the message"})
+	}
+
+	if applied {
+		if _, err := adjustPinCount(db, channelId, -1); err != nil {
+			log.Error(err)
+		}
+
+		if serverId != "" {
+			recordAudit(serverId, userId.String(), auditActionMessageUnpin, "message", message.MessageId.String(), map[string]interface{}{"channel_id": channelId}, nil)
+		}
+		broadcastPinUpdate(channelId, message, false, userId, time.Now())
+	}
+
+	return nil
+}
+
+// GetPinnedMessages returns the pinned messages of the channel, the most
+// recently pinned first.
+func GetPinnedMessages(c *fiber.Ctx) error {
+	db := database.DB
+	channelId := c.Params("channelId")
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the pinned messages"})
+	}
+
+	if !containsUUID(getAllUsersFromChannel(channelId, db), userId) {
+		return c.Status(403).JSON(fiber.Map{"error": "You are not in this channel"})
+	}
+
+	type PinnedMessage struct {
+		models.Message
+		PinnedBy gocql.UUID `json:"pinnedBy"`
+		PinnedAt time.Time  `json:"pinnedAt"`
+	}
+
+	pins := []PinnedMessage{}
+	queryPins := "SELECT message_id, pinned_by, pinned_at FROM pinned_messages WHERE channel_id = ?"
+	scanner := db.Query(queryPins, channelId).Iter().Scanner()
+	for scanner.Next() {
+		var pin PinnedMessage
+		var messageId gocql.UUID
+		if err := scanner.Scan(&messageId, &pin.PinnedBy, &pin.PinnedAt); err != nil {
+			log.Error(err)
+			continue
+		}
+
+		message, err := getMessage(channelId, messageId)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		pin.Message = message
+		pins = append(pins, pin)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the pinned messages"})
+	}
+
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].PinnedAt.After(pins[j].PinnedAt)
+	})
+
+	return c.JSON(pins)
+}
+
+// getPinnableMessage checks the caller may pin or unpin the message of the
+// request: anyone in a DM, only the server owner in a server so a member
+// can't take every place. The server is the one of the channel, for the
+// audit log.
+func getPinnableMessage(c *fiber.Ctx) (gocql.UUID, string, models.Message, int, error) {
+	db := database.DB
+	channelId := c.Params("channelId")
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return userId, "", models.Message{}, 500, fmt.Errorf("Couldn't update the pinned messages")
+	}
+
+	messageId, err := gocql.ParseUUID(c.Params("messageId"))
+	if err != nil {
+		return userId, "", models.Message{}, 404, fmt.Errorf("Message not found")
+	}
+
+	if !containsUUID(getAllUsersFromChannel(channelId, db), userId) {
+		return userId, "", models.Message{}, 403, fmt.Errorf("You are not in this channel")
+	}
+
+	message, err := getMessage(channelId, messageId)
+	if err != nil {
+		log.Error(err)
+		return userId, "", models.Message{}, 404, fmt.Errorf("Message not found")
+	}
+
+	serverId, err := getChannelServer(db, userId, channelId, message.ServerId)
+	if err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return userId, "", models.Message{}, 404, fmt.Errorf("Channel not found")
+	}
+
+	if serverId != "" {
+		server, err := utils.GetServerInformations(serverId)
+		if err != nil || !canManageServer(server, userId.String()) {
+			return userId, "", models.Message{}, 403, fmt.Errorf("You can't pin messages in this channel")
+		}
+	}
+
+	return userId, serverId, message, 200, nil
+}
+
+// adjustPinCount moves the number of pins of the channel by delta, with a
+// LWT so concurrent pins can't go over maxPinsByChannel. It's false when the
+// channel is full.
+func adjustPinCount(db *gocql.Session, channelId string, delta int) (bool, error) {
+	queryPinCount := "SELECT pin_count FROM channel_pin_counts WHERE channel_id = ?"
+	queryUpdatePinCount := "UPDATE channel_pin_counts SET pin_count = ? WHERE channel_id = ? IF pin_count = ?"
+
+	for attempt := 0; attempt < 10; attempt++ {
+		var count int
+		err := db.Query(queryPinCount, channelId).Scan(&count)
+		if err == gocql.ErrNotFound {
+			// Channels pinned in before the counter existed start from their
+			// current pins.
+			if err := db.Query("SELECT COUNT(*) FROM pinned_messages WHERE channel_id = ?", channelId).Scan(&count); err != nil {
+				return false, err
+			}
+
+			queryCreatePinCount := "INSERT INTO channel_pin_counts (channel_id, pin_count) VALUES (?, ?) IF NOT EXISTS"
+			if _, err := db.Query(queryCreatePinCount, channelId, count).MapScanCAS(map[string]interface{}{}); err != nil {
+				return false, err
+			}
+			continue
+		} else if err != nil {
+			return false, err
+		}
+
+		next := count + delta
+		if delta > 0 && next > maxPinsByChannel {
+			return false, nil
+		}
+		if next < 0 {
+			next = 0
+		}
+
+		applied, err := db.Query(queryUpdatePinCount, next, channelId, count).MapScanCAS(map[string]interface{}{})
+		if err != nil {
+			return false, err
+		}
+
+		if applied {
+			return true, nil
+		}
+	}
+
+	return false, fmt.Errorf("the pin count of %s kept changing", channelId)
+}
+
+// getMessage fetches a single message of the channel with its sender,
+// attachments and embeds.
+func getMessage(channelId string, messageId gocql.UUID) (models.Message, error) {
+	db := database.DB
+	var message models.Message
+
+	queryMessage := "SELECT channel_id, created_at, message_id, content, mentions, mentions_roles, mention_everyone, attachments, sender_id, server_id FROM messages WHERE channel_id = ? AND message_id = ? ALLOW FILTERING"
+	if err := db.Query(queryMessage, channelId, messageId).Scan(&message.ChannelId, &message.CreatedAt, &message.MessageId, &message.Content, &message.Mentions, &message.MentionsRoles, &message.MentionEveryone, &message.AttachmentIds, &message.UserId, &message.ServerId); err != nil {
+		return message, err
+	}
+
+	user, err := GetUserById(message.UserId)
+	if err != nil {
+		user = deletedUser()
+	}
+
+	message.User = user
+	message.Attachments = getMessageAttachments(message.AttachmentIds)
+	message.Embeds = getMessageEmbeds(message.MessageId)
+
+	return message, nil
+}
+
+func broadcastPinUpdate(channelId string, message models.Message, pinned bool, userId gocql.UUID, at time.Time) {
+	db := database.DB
+
+	messageToSend := &protobuf.ServerMessage{
+		Type: "pin_update",
+		Payload: &protobuf.ServerMessage_PinUpdate{
+			PinUpdate: &protobuf.PinUpdate{
+				ChannelId: channelId,
+				Pinned:    pinned,
+				PinnedBy:  userId.String(),
+				PinnedAt:  timestamppb.New(at),
+				Message:   userMessageToProtobuf(message.User, message, timestamppb.New(message.CreatedAt)),
+			},
+		},
+	}
+
+	data, err := proto.Marshal(messageToSend)
+	if err != nil {
+		fmt.Println("Error when transforming the message into protobuf", err)
+		return
+	}
+
+	for _, member := range getAllUsersFromChannel(channelId, db) {
+		sendToUser(member, data, nil)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/ratelimit"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	messageUserLimiter    *ratelimit.Limiter
+	messageChannelLimiter *ratelimit.Limiter
+	websocketLimiter      *ratelimit.Limiter
+	messageDuplicates     *ratelimit.Duplicates
+)
+
+// InitRateLimits sets the message and websocket limits up, each one can be
+// changed with its RATE_LIMIT_* variable.
+func InitRateLimits() {
+	messageUserLimiter = ratelimit.New(ratelimit.FromEnv("RATE_LIMIT_MESSAGES_USER", ratelimit.Limit{Burst: 5, Per: 5 * time.Second}))
+	messageChannelLimiter = ratelimit.New(ratelimit.FromEnv("RATE_LIMIT_MESSAGES_CHANNEL", ratelimit.Limit{Burst: 20, Per: 5 * time.Second}))
+	websocketLimiter = ratelimit.New(ratelimit.FromEnv("RATE_LIMIT_WEBSOCKET", ratelimit.Limit{Burst: 30, Per: 10 * time.Second}))
+	messageDuplicates = ratelimit.NewDuplicates(ratelimit.FromEnv("RATE_LIMIT_DUPLICATES", ratelimit.Limit{Burst: 3, Per: 30 * time.Second}))
+}
+
+// limitMessage applies the posting limits of the user and of the channel.
+// When one is reached the request is answered with a 429 and the user is
+// told on the websocket, handled is then true.
+func limitMessage(c *fiber.Ctx, channelId string, content string) (bool, error) {
+	userId := c.Locals("user_id").(string)
+
+	if scope, retryAfter, limited := messageLimited(userId, channelId, content); limited {
+		return true, tooManyRequests(c, userId, scope, retryAfter)
+	}
+
+	return false, nil
+}
+
+// messageLimited tells if the user may post the content in the channel now,
+// when not it's limited in scope until retryAfter.
+func messageLimited(userId string, channelId string, content string) (string, time.Duration, bool) {
+	if ok, retryAfter := messageUserLimiter.Allow(userId); !ok {
+		return "messages", retryAfter, true
+	}
+
+	if ok, retryAfter := messageChannelLimiter.Allow(channelId); !ok {
+		return "channel", retryAfter, true
+	}
+
+	if content != "" {
+		if duplicate, retryAfter := messageDuplicates.Seen(userId, content); duplicate {
+			return "duplicate", retryAfter, true
+		}
+	}
+
+	return "", 0, false
+}
+
+func tooManyRequests(c *fiber.Ctx, userId string, scope string, retryAfter time.Duration) error {
+	if userUUID, err := gocql.ParseUUID(userId); err == nil {
+		sendRateLimited(userUUID, nil, scope, retryAfter)
+	}
+
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "You are doing this too fast", "scope": scope, "retry_after": retryAfter.Milliseconds()})
+}
+
+// sendRateLimited tells the user something they did was refused, on the
+// given connection only when there's one.
+func sendRateLimited(userId gocql.UUID, conn *Connection, scope string, retryAfter time.Duration) {
+	data, err := proto.Marshal(&protobuf.ServerMessage{
+		Type: "rate_limited",
+		Payload: &protobuf.ServerMessage_RateLimited{
+			RateLimited: &protobuf.RateLimited{
+				Scope:        scope,
+				RetryAfterMs: retryAfter.Milliseconds(),
+			},
+		},
+	})
+	if err != nil {
+		fmt.Println("Error when transforming the message into protobuf", err)
+		return
+	}
+
+	if conn != nil {
+		conn.Send(data)
+		return
+	}
+
+	sendToUser(userId, data, nil)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/protobuf/proto"
+)
+
+func AckMessage(c *fiber.Ctx) error {
+	channelId := c.Params("channelId")
+	messageId := c.Params("messageId")
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't mark the channel as read"})
+	}
+
+	readState, err := ackMessage(userId, channelId, messageId, nil)
+	if err != nil {
+		log.Error(err)
+		return c.Status(404).JSON(fiber.Map{"error": "Couldn't mark the channel as read"})
+	}
+
+	return c.JSON(readState)
+}
+
+// ackMessage marks everything up to messageId as read in the channel and
+// lets the other sessions of the user know about it. from is the websocket
+// the ack came through, if any, it doesn't need to be told.
+func ackMessage(userId gocql.UUID, channelId string, messageId string, from *Connection) (models.ReadState, error) {
+	db := database.DB
+
+	messageUUID, err := gocql.ParseUUID(messageId)
+	if err != nil {
+		return models.ReadState{}, fmt.Errorf("Invalid message id")
+	}
+
+	var member gocql.UUID
+	queryCheckMember := "SELECT user_id FROM channel_to_users WHERE channel_id = ? AND user_id = ?"
+	if err := db.Query(queryCheckMember, channelId, userId).Scan(&member); err != nil {
+		return models.ReadState{}, fmt.Errorf("User not in channel")
+	}
+
+	var createdAt time.Time
+	var serverId string
+	queryGetMessage := "SELECT created_at, server_id FROM messages WHERE channel_id = ? AND message_id = ? ALLOW FILTERING"
+	if err := db.Query(queryGetMessage, channelId, messageUUID).Scan(&createdAt, &serverId); err != nil {
+		return models.ReadState{}, fmt.Errorf("Message not found")
+	}
+
+	current := getReadState(userId, serverId, channelId)
+	if !current.LastReadAt.Before(createdAt) {
+		// Acks arriving late from another session never move the state back.
+		return current, nil
+	}
+
+	if err := setReadState(userId, serverId, channelId, messageUUID, createdAt, from); err != nil {
+		return models.ReadState{}, err
+	}
+
+	return getReadState(userId, serverId, channelId), nil
+}
+
+func setReadState(userId gocql.UUID, serverId string, channelId string, messageId gocql.UUID, createdAt time.Time, from *Connection) error {
+	db := database.DB
+
+	queryUpdateReadState := "INSERT INTO read_states (user_id, channel_id, last_message_id, last_read_at) VALUES (?, ?, ?, ?)"
+	if err := db.Query(queryUpdateReadState, userId, channelId, messageId, createdAt).Exec(); err != nil {
+		return err
+	}
+
+	messageToSend := &protobuf.ServerMessage{
+		Type: "read_state_update",
+		Payload: &protobuf.ServerMessage_ReadState{
+			ReadState: readStateToProtobuf(getReadState(userId, serverId, channelId)),
+		},
+	}
+
+	data, err := proto.Marshal(messageToSend)
+	if err != nil {
+		fmt.Println("Error when transforming the message into protobuf", err)
+		return nil
+	}
+
+	sendToUser(userId, data, from)
+
+	return nil
+}
+
+// Counting the unread messages means reading them, so no more than
+// maxUnreadScan + 1 are read. A channel with more than maxUnreadScan unread
+// messages from others reports exactly maxUnreadScan, which the client shows
+// as "99+". Sending a message marks the channel as read, so the messages of
+// the user rarely take room in the scan.
+const maxUnreadScan = 100
+
+// getReadState counts what the user hasn't read yet in the channel, their
+// own messages are never unread. Mentions the user opted out of through their
+// notification settings are not counted.
+func getReadState(userId gocql.UUID, serverId string, channelId string) models.ReadState {
+	db := database.DB
+	readState := models.ReadState{UserId: userId, ChannelId: channelId}
+	policy := getNotificationPolicy(userId, serverId, channelId)
+	readState.Muted = policy.Muted || policy.Level == notificationLevelNothing
+
+	queryReadState := "SELECT last_message_id, last_read_at FROM read_states WHERE user_id = ? AND channel_id = ?"
+	if err := db.Query(queryReadState, userId, channelId).Scan(&readState.LastMessageId, &readState.LastReadAt); err != nil && err != gocql.ErrNotFound {
+		log.Error(err)
+	}
+
+	queryUnread := "SELECT sender_id, mentions, mention_everyone FROM messages WHERE channel_id = ? AND created_at > ? LIMIT ?"
+	scanner := db.Query(queryUnread, channelId, readState.LastReadAt, maxUnreadScan+1).Iter().Scanner()
+	for scanner.Next() {
+		var senderId gocql.UUID
+		var mentions []gocql.UUID
+		var mentionEveryone bool
+		if err := scanner.Scan(&senderId, &mentions, &mentionEveryone); err != nil {
+			log.Error(err)
+			continue
+		}
+
+		if senderId == userId {
+			continue
+		}
+
+		readState.UnreadCount++
+		direct := containsUUID(mentions, userId)
+		if (direct || mentionEveryone) && policy.allowsMention(direct) {
+			readState.MentionCount++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+	}
+
+	if readState.UnreadCount > maxUnreadScan {
+		readState.UnreadCount = maxUnreadScan
+	}
+
+	return readState
+}
+
+func getServerUnread(serverId string, userId gocql.UUID) models.ServerUnread {
+	db := database.DB
+	serverUnread := models.ServerUnread{ServerId: serverId}
+
+	queryGetChannels := "SELECT channel_id FROM channels WHERE server_id = ?"
+	scanner := db.Query(queryGetChannels, serverId).Iter().Scanner()
+	for scanner.Next() {
+		var channelId string
+		if err := scanner.Scan(&channelId); err != nil {
+			log.Error(err)
+			continue
+		}
+
+		readState := getReadState(userId, serverId, channelId)
+		if readState.Muted {
+			continue
+		}
+
+		serverUnread.UnreadCount += readState.UnreadCount
+		serverUnread.MentionCount += readState.MentionCount
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+	}
+
+	return serverUnread
+}
+
+func deleteReadStates(db *gocql.Session, users []gocql.UUID, channelId string) {
+	queryDeleteReadState := "DELETE FROM read_states WHERE user_id = ? AND channel_id = ?"
+	for _, userId := range users {
+		if err := db.Query(queryDeleteReadState, userId, channelId).Exec(); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+func readStateToProtobuf(readState models.ReadState) *protobuf.ReadState {
+	message := &protobuf.ReadState{
+		ChannelId:    readState.ChannelId,
+		UnreadCount:  int32(readState.UnreadCount),
+		MentionCount: int32(readState.MentionCount),
+		Muted:        readState.Muted,
+	}
+
+	if readState.LastMessageId != (gocql.UUID{}) {
+		message.LastMessageId = readState.LastMessageId.String()
+	}
+
+	return message
+}
+
+func serverUnreadToProtobuf(serverUnread models.ServerUnread) *protobuf.ServerUnread {
+	return &protobuf.ServerUnread{
+		ServerId:     serverUnread.ServerId,
+		UnreadCount:  int32(serverUnread.UnreadCount),
+		MentionCount: int32(serverUnread.MentionCount),
+	}
+}
@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	scheduledKindMessage  = "message"
+	scheduledKindReminder = "reminder"
+
+	scheduledStatusPending    = "pending"
+	scheduledStatusProcessing = "processing"
+	// A reminder due while its user is offline waits in this status, it's
+	// delivered when they connect.
+	scheduledStatusFired = "fired"
+
+	// A backend handling an item holds it until its claim expires, another
+	// one takes it over after that, in case the first one died meanwhile.
+	schedulerClaimDuration = 2 * time.Minute
+
+	// The queue is spread over a few partitions so a single one doesn't
+	// hold every pending item.
+	schedulerShards       = 8
+	schedulerPollInterval = 5 * time.Second
+	maxScheduleDelay      = 365 * 24 * time.Hour
+	maxScheduledByUser    = 100
+)
+
+// Pending items live in two tables: scheduled_items, partitioned by user for
+// the listing, and scheduled_queue, ordered by due date for the scheduler.
+// Both survive restarts, anything that became due while the backend was
+// down is handled on the first poll, and an item left in processing by a
+// backend that died is taken over once its claim expired. An item is
+// therefore sent at least once, twice if the backend died between sending
+// it and deleting it.
+
+func ScheduleMessage(c *fiber.Ctx) error {
+	type BodyRequest struct {
+		ChannelId     string       `json:"channel_id"`
+		ServerId      string       `json:"server_id"`
+		Content       string       `json:"content"`
+		AttachmentIds []gocql.UUID `json:"attachment_ids"`
+		SendAt        time.Time    `json:"send_at"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the scheduled message"})
+	}
+
+	if body.Content == "" && len(body.AttachmentIds) == 0 {
+		return c.Status(422).JSON(fiber.Map{"error": "The message is empty"})
+	}
+
+	item := models.ScheduledItem{
+		Kind:          scheduledKindMessage,
+		ChannelId:     body.ChannelId,
+		ServerId:      body.ServerId,
+		Content:       body.Content,
+		AttachmentIds: body.AttachmentIds,
+		DueAt:         body.SendAt,
+	}
+
+	return createScheduledItem(c, item)
+}
+
+func CreateReminder(c *fiber.Ctx) error {
+	type BodyRequest struct {
+		ChannelId string     `json:"channel_id"`
+		MessageId gocql.UUID `json:"message_id"`
+		Note      string     `json:"note"`
+		RemindAt  time.Time  `json:"remind_at"`
+	}
+
+	var body BodyRequest
+	err := c.BodyParser(&body)
+	if err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the reminder"})
+	}
+
+	message, err := getMessage(body.ChannelId, body.MessageId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Message not found"})
+	}
+
+	item := models.ScheduledItem{
+		Kind:      scheduledKindReminder,
+		ChannelId: message.ChannelId,
+		ServerId:  message.ServerId,
+		MessageId: message.MessageId,
+		Note:      body.Note,
+		DueAt:     body.RemindAt,
+	}
+
+	return createScheduledItem(c, item)
+}
+
+func GetScheduledItems(c *fiber.Ctx) error {
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch your scheduled items"})
+	}
+
+	items, err := getScheduledItems(userId)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch your scheduled items"})
+	}
+
+	return c.JSON(items)
+}
+
+func CancelScheduledItem(c *fiber.Ctx) error {
+	db := database.DB
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't cancel the scheduled item"})
+	}
+
+	itemId, err := gocql.ParseUUID(c.Params("itemId"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Scheduled item not found"})
+	}
+
+	item, err := getScheduledItem(userId, itemId)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Scheduled item not found"})
+	}
+
+	// Only pending items can be cancelled, one being sent goes through. A
+	// fired reminder is dismissed.
+	status := scheduledStatusPending
+	if item.Status == scheduledStatusFired {
+		status = scheduledStatusFired
+	}
+
+	queryCancelItem := "DELETE FROM scheduled_items WHERE user_id = ? AND item_id = ? IF status = ?"
+	applied, err := db.Query(queryCancelItem, userId, itemId, status).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't cancel the scheduled item"})
+	}
+
+	if !applied {
+		return c.Status(409).JSON(fiber.Map{"error": "This item is already being sent"})
+	}
+
+	dequeueScheduledItem(db, item)
+
+	return nil
+}
+
+func createScheduledItem(c *fiber.Ctx, item models.ScheduledItem) error {
+	db := database.DB
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't schedule the item"})
+	}
+
+	now := time.Now()
+	if !item.DueAt.After(now) || item.DueAt.After(now.Add(maxScheduleDelay)) {
+		return c.Status(422).JSON(fiber.Map{"error": "Invalid date"})
+	}
+
+	if !containsUUID(getAllUsersFromChannel(item.ChannelId, db), userId) {
+		return c.Status(403).JSON(fiber.Map{"error": "You are not in this channel"})
+	}
+
+	var count int
+	queryCountItems := "SELECT COUNT(*) FROM scheduled_items WHERE user_id = ?"
+	if err := db.Query(queryCountItems, userId).Scan(&count); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't schedule the item"})
+	}
+
+	if count >= maxScheduledByUser {
+		return c.Status(409).JSON(fiber.Map{"error": fmt.Sprintf("You can't have more than %d scheduled items", maxScheduledByUser)})
+	}
+
+	item.Id = gocql.MustRandomUUID()
+	item.UserId = userId
+	item.Status = scheduledStatusPending
+	item.CreatedAt = now
+
+	queryCreateItem := "INSERT INTO scheduled_items (user_id, item_id, kind, channel_id, server_id, message_id, content, attachments, note, due_at, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if err := db.Query(queryCreateItem, item.UserId, item.Id, item.Kind, item.ChannelId, item.ServerId, item.MessageId, item.Content, item.AttachmentIds, item.Note, item.DueAt, item.Status, item.CreatedAt).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't schedule the item"})
+	}
+
+	queryQueueItem := "INSERT INTO scheduled_queue (shard, due_at, item_id, user_id) VALUES (?, ?, ?, ?)"
+	if err := db.Query(queryQueueItem, schedulerShard(item.Id), item.DueAt, item.Id, item.UserId).Exec(); err != nil {
+		log.Error(err)
+		db.Query("DELETE FROM scheduled_items WHERE user_id = ? AND item_id = ?", item.UserId, item.Id).Exec()
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't schedule the item"})
+	}
+
+	return c.JSON(item)
+}
+
+// StartScheduler polls the queue for the items that became due.
+func StartScheduler() {
+	go func() {
+		ticker := time.NewTicker(schedulerPollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			runDueItems(time.Now())
+		}
+	}()
+}
+
+func runDueItems(now time.Time) {
+	db := database.DB
+
+	queryDueItems := "SELECT due_at, user_id, item_id FROM scheduled_queue WHERE shard = ? AND due_at <= ?"
+	for shard := 0; shard < schedulerShards; shard++ {
+		scanner := db.Query(queryDueItems, shard, now).Iter().Scanner()
+		for scanner.Next() {
+			var dueAt time.Time
+			var userId, itemId gocql.UUID
+			if err := scanner.Scan(&dueAt, &userId, &itemId); err != nil {
+				log.Error(err)
+				continue
+			}
+
+			runScheduledItem(db, userId, itemId, dueAt)
+		}
+
+		if err := scanner.Err(); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+// runScheduledItem claims the item first, so it's only handled once even
+// with several backends polling the same queue.
+func runScheduledItem(db *gocql.Session, userId gocql.UUID, itemId gocql.UUID, dueAt time.Time) {
+	item, err := getScheduledItem(userId, itemId)
+	if err == gocql.ErrNotFound {
+		// Cancelled in between, only the queue entry is left.
+		dequeueScheduledItem(db, models.ScheduledItem{Id: itemId, UserId: userId, DueAt: dueAt})
+		return
+	} else if err != nil {
+		log.Error(err)
+		return
+	}
+
+	var applied bool
+	claimUntil := time.Now().Add(schedulerClaimDuration)
+	switch {
+	case item.Status == scheduledStatusPending:
+		queryClaimItem := "UPDATE scheduled_items SET status = ?, claim_until = ? WHERE user_id = ? AND item_id = ? IF status = ?"
+		applied, err = db.Query(queryClaimItem, scheduledStatusProcessing, claimUntil, userId, itemId, scheduledStatusPending).MapScanCAS(map[string]interface{}{})
+	case item.Status == scheduledStatusProcessing && time.Now().After(item.ClaimUntil):
+		var previous interface{}
+		if !item.ClaimUntil.IsZero() {
+			previous = item.ClaimUntil
+		}
+
+		queryTakeOverItem := "UPDATE scheduled_items SET claim_until = ? WHERE user_id = ? AND item_id = ? IF status = ? AND claim_until = ?"
+		applied, err = db.Query(queryTakeOverItem, claimUntil, userId, itemId, scheduledStatusProcessing, previous).MapScanCAS(map[string]interface{}{})
+	case item.Status == scheduledStatusProcessing:
+		// Another backend is on it.
+		return
+	default:
+		// Fired, it waits for its user and no longer belongs in the queue.
+		dequeueScheduledItem(db, item)
+		return
+	}
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if !applied {
+		return
+	}
+
+	if item.Kind == scheduledKindReminder && !isConnected(userId) {
+		queryFireItem := "UPDATE scheduled_items SET status = ? WHERE user_id = ? AND item_id = ? IF status = ?"
+		if _, err := db.Query(queryFireItem, scheduledStatusFired, userId, itemId, scheduledStatusProcessing).MapScanCAS(map[string]interface{}{}); err != nil {
+			log.Error(err)
+			return
+		}
+		dequeueScheduledItem(db, item)
+		return
+	}
+
+	// A scheduled message goes through the same limits as any other, one
+	// over them is postponed until it can be sent.
+	if item.Kind == scheduledKindMessage {
+		if _, retryAfter, limited := messageLimited(userId.String(), item.ChannelId, item.Content); limited {
+			postponeScheduledItem(db, item, time.Now().Add(retryAfter))
+			return
+		}
+	}
+
+	switch item.Kind {
+	case scheduledKindMessage:
+		err = postScheduledMessage(item)
+	case scheduledKindReminder:
+		err = sendReminder(item)
+	}
+	if err != nil {
+		log.Errorf("Error when running scheduled item %s: %v", item.Id, err)
+	}
+
+	if err := db.Query("DELETE FROM scheduled_items WHERE user_id = ? AND item_id = ?", userId, itemId).Exec(); err != nil {
+		log.Error(err)
+	}
+	dequeueScheduledItem(db, item)
+}
+
+// deliverFiredReminders sends the reminders that fired while the user was
+// offline, each one is removed first so two connections opened at once
+// don't both get it.
+func deliverFiredReminders(userId gocql.UUID) {
+	db := database.DB
+
+	items, err := getScheduledItems(userId)
+	if err != nil {
+		return
+	}
+
+	queryDeliverItem := "DELETE FROM scheduled_items WHERE user_id = ? AND item_id = ? IF status = ?"
+	for _, item := range items {
+		if item.Status != scheduledStatusFired {
+			continue
+		}
+
+		applied, err := db.Query(queryDeliverItem, userId, item.Id, scheduledStatusFired).MapScanCAS(map[string]interface{}{})
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		if applied {
+			if err := sendReminder(item); err != nil {
+				log.Error(err)
+			}
+		}
+	}
+}
+
+func postScheduledMessage(item models.ScheduledItem) error {
+	db := database.DB
+
+	user, err := GetUserById(item.UserId)
+	if err != nil {
+		return err
+	}
+
+	// The user may have left the channel since the message was written.
+	if !containsUUID(getAllUsersFromChannel(item.ChannelId, db), item.UserId) {
+		return fmt.Errorf("User not in channel anymore")
+	}
+
+	// The server the client gave when scheduling only picks where to look
+	// for the channel, its kind comes from the channel itself.
+	serverId, err := getChannelServer(db, item.UserId, item.ChannelId, item.ServerId)
+	if err != nil {
+		return err
+	}
+
+	message := models.Message{
+		ChannelId:     item.ChannelId,
+		ServerId:      serverId,
+		Content:       item.Content,
+		AttachmentIds: item.AttachmentIds,
+		User:          user,
+	}
+
+	if serverId == "" {
+		_, err = sendDirectMessage(&message)
+	} else {
+		_, err = sendChannelMessage(&message)
+	}
+
+	return err
+}
+
+func sendReminder(item models.ScheduledItem) error {
+	reminder := &protobuf.Reminder{
+		Id:        item.Id.String(),
+		ChannelId: item.ChannelId,
+		ServerId:  item.ServerId,
+		Note:      item.Note,
+	}
+
+	if message, err := getMessage(item.ChannelId, item.MessageId); err == nil {
+		reminder.Message = userMessageToProtobuf(message.User, message, timestamppb.New(message.CreatedAt))
+	}
+
+	data, err := proto.Marshal(&protobuf.ServerMessage{
+		Type: "reminder",
+		Payload: &protobuf.ServerMessage_Reminder{
+			Reminder: reminder,
+		},
+	})
+	if err != nil {
+		fmt.Println("Error when transforming the message into protobuf", err)
+		return err
+	}
+
+	sendToUser(item.UserId, data, nil)
+
+	return nil
+}
+
+// postponeScheduledItem puts a claimed item back in the queue, due at dueAt.
+func postponeScheduledItem(db *gocql.Session, item models.ScheduledItem, dueAt time.Time) {
+	queryQueueItem := "INSERT INTO scheduled_queue (shard, due_at, item_id, user_id) VALUES (?, ?, ?, ?)"
+	if err := db.Query(queryQueueItem, schedulerShard(item.Id), dueAt, item.Id, item.UserId).Exec(); err != nil {
+		log.Error(err)
+		return
+	}
+
+	queryPostponeItem := "UPDATE scheduled_items SET status = ?, due_at = ?, claim_until = null WHERE user_id = ? AND item_id = ? IF status = ?"
+	applied, err := db.Query(queryPostponeItem, scheduledStatusPending, dueAt, item.UserId, item.Id, scheduledStatusProcessing).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if applied {
+		dequeueScheduledItem(db, item)
+	}
+}
+
+func dequeueScheduledItem(db *gocql.Session, item models.ScheduledItem) {
+	queryDequeueItem := "DELETE FROM scheduled_queue WHERE shard = ? AND due_at = ? AND item_id = ?"
+	if err := db.Query(queryDequeueItem, schedulerShard(item.Id), item.DueAt, item.Id).Exec(); err != nil {
+		log.Error(err)
+	}
+}
+
+func getScheduledItem(userId gocql.UUID, itemId gocql.UUID) (models.ScheduledItem, error) {
+	db := database.DB
+	var item models.ScheduledItem
+
+	queryItem := "SELECT user_id, item_id, kind, channel_id, server_id, message_id, content, attachments, note, due_at, status, created_at, claim_until FROM scheduled_items WHERE user_id = ? AND item_id = ?"
+	err := db.Query(queryItem, userId, itemId).Scan(&item.UserId, &item.Id, &item.Kind, &item.ChannelId, &item.ServerId, &item.MessageId, &item.Content, &item.AttachmentIds, &item.Note, &item.DueAt, &item.Status, &item.CreatedAt, &item.ClaimUntil)
+
+	return item, err
+}
+
+func getScheduledItems(userId gocql.UUID) ([]models.ScheduledItem, error) {
+	db := database.DB
+	items := []models.ScheduledItem{}
+
+	queryItems := "SELECT user_id, item_id, kind, channel_id, server_id, message_id, content, attachments, note, due_at, status, created_at FROM scheduled_items WHERE user_id = ?"
+	scanner := db.Query(queryItems, userId).Iter().Scanner()
+	for scanner.Next() {
+		var item models.ScheduledItem
+		if err := scanner.Scan(&item.UserId, &item.Id, &item.Kind, &item.ChannelId, &item.ServerId, &item.MessageId, &item.Content, &item.AttachmentIds, &item.Note, &item.DueAt, &item.Status, &item.CreatedAt); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func schedulerShard(itemId gocql.UUID) int {
+	return int(itemId[15]) % schedulerShards
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/storage"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// PresignServerMedia returns where to upload the next version of the icon or
+// the banner of a server, the version is picked by the backend.
+func PresignServerMedia(c *fiber.Ctx) error {
+	serverId := c.Params("serverId")
+	media := c.Params("media")
+
+	server, status, err := getManagedServer(serverId, media, c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	version := serverMediaVersion(server, media) + 1
+	url, err := presignPut(serverMediaKey(serverId, media, version))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the presigned URL"})
+	}
+
+	return c.Status(200).JSON(fiber.Map{"url": url, "version": version})
+}
+
+// UpdateMediaForServer processes the uploaded version of the media, points
+// the server to it, deletes the previous one and lets every member know.
+func UpdateMediaForServer(c *fiber.Ctx) error {
+	db := database.DB
+	serverId := c.Params("serverId")
+	media := c.Params("media")
+
+	server, status, err := getManagedServer(serverId, media, c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	version, err := strconv.Atoi(c.Params("version"))
+	oldVersion := serverMediaVersion(server, media)
+	if err != nil || version != oldVersion+1 {
+		return c.Status(409).JSON(fiber.Map{"error": "This media has been updated in the meantime"})
+	}
+
+	sizes := mediaSizes[media]
+	objectKey := serverMediaKey(serverId, media, version)
+	if err := processMedia(objectKey, sizes); err != nil {
+		return mediaError(c, err)
+	}
+
+	url := storage.Store.URL(objectKey)
+	before := server
+	if media == "icon" {
+		server.Icon, server.IconVersion = url, version
+	} else {
+		server.Banner, server.BannerVersion = url, version
+	}
+
+	query := fmt.Sprintf("UPDATE servers SET %s = ?, %s_version = ? WHERE server_id = ?", media, media)
+	if err := db.Query(query, url, version, serverId).Exec(); err != nil {
+		log.Errorf("Error when updating medias: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't update the media"})
+	}
+
+	recordAudit(serverId, c.Locals("user_id").(string), auditActionServerUpdate, "server", serverId, before, server)
+	refreshServerListing(serverId)
+
+	if oldVersion > 0 {
+		if err := deleteMedia(serverMediaKey(serverId, media, oldVersion), sizes); err != nil {
+			log.Errorf("Error when deleting old media: %v", err)
+		}
+	}
+
+	var users []gocql.UUID
+	queryGetUsersOfServer := "SELECT users FROM server_to_users WHERE server_id = ?"
+	if err := db.Query(queryGetUsersOfServer, serverId).Scan(&users); err != nil {
+		log.Error(err)
+	}
+
+	broadcastServerChanges(users, Options{Server: &server}, "server_update")
+
+	return c.Status(200).JSON(fiber.Map{"url": url, "version": version})
+}
+
+// getManagedServer fetches the server whose media the user wants to change,
+// with the status to answer when it's not possible.
+func getManagedServer(serverId string, media string, userId string) (models.Server, int, error) {
+	if media != "icon" && media != "banner" {
+		return models.Server{}, 422, fmt.Errorf("Unknown media")
+	}
+
+	server, err := utils.GetServerInformations(serverId)
+	if err != nil {
+		log.Error(err)
+		return server, 404, fmt.Errorf("Server not found")
+	}
+
+	if !canManageServer(server, userId) {
+		return server, 403, fmt.Errorf("You can't change the medias of this server")
+	}
+
+	return server, 200, nil
+}
+
+// canManageServer tells if the user may change the settings of the server,
+// which is only its owner for now.
+func canManageServer(server models.Server, userId string) bool {
+	return server.Owner.String() == userId
+}
+
+// serverMediaVersion is the version of the media currently in use. Servers
+// created before the versions were stored still use their first banner.
+func serverMediaVersion(server models.Server, media string) int {
+	if media == "icon" {
+		return server.IconVersion
+	}
+
+	if server.BannerVersion == 0 && server.Banner != "" {
+		return 1
+	}
+
+	return server.BannerVersion
+}
+
+func serverMediaKey(serverId string, media string, version int) string {
+	return fmt.Sprintf("server_%s/%s_%s_v%d.webp", media, media, serverId, version)
+}
+
+func serverToProtobuf(server models.Server) *protobuf.Server {
+	return &protobuf.Server{
+		ServerId:    server.ServerId,
+		Banner:      server.Banner,
+		Description: server.Description,
+		Name:        server.Name,
+		Owner:       server.Owner.String(),
+		Status:      server.Status,
+		Icon:        server.Icon,
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
 	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
 	"github.com/gocql/gocql"
-	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 	"google.golang.org/protobuf/proto"
@@ -31,8 +30,6 @@ var rollback []Deletion
 func JoinServer(c *fiber.Ctx) error {
 	db := database.DB
 	var serverId string
-	var serverInformations models.Server
-	var channels []string
 	var Invitation Invitation
 	userId := c.Locals("user_id").(string)
 
@@ -48,6 +45,16 @@ func JoinServer(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Invitation doesn't exist"})
 	}
 
+	return joinServer(c, userId, serverId, map[string]interface{}{"invitation_id": Invitation.Id})
+}
+
+// joinServer makes the user a member of the server and of all its channels,
+// whatever let them in: an invitation or the server being public.
+func joinServer(c *fiber.Ctx, userId string, serverId string, auditDetails map[string]interface{}) error {
+	db := database.DB
+	var serverInformations models.Server
+	var channels []string
+
 	queryGetAllChannels := "SELECT channel_id FROM channels WHERE server_id = ?"
 	scanner := db.Query(queryGetAllChannels, serverId).Iter().Scanner()
 	for scanner.Next() {
@@ -87,8 +94,8 @@ func JoinServer(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Couldn't join the server"})
 	}
 
-	queryServerInformations := "SELECT * FROM servers WHERE server_id = ?"
-	if err := db.Query(queryServerInformations, serverId).Scan(&serverInformations.ServerId, &serverInformations.CreatedAt, &serverInformations.Banner, &serverInformations.Description, &serverInformations.Name, &serverInformations.Owner, &serverInformations.Status); err != nil {
+	serverInformations, err := utils.GetServerInformations(serverId)
+	if err != nil {
 		log.Error(err)
 	}
 
@@ -99,6 +106,8 @@ func JoinServer(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "An error occured while leaving the server"})
 	}
 
+	recordAudit(serverId, userId, auditActionMemberJoin, "user", userId, nil, auditDetails)
+	refreshServerListing(serverId)
 	broadcastServerChanges(users, Options{UserId: &userId, Server: &serverInformations}, "server_join")
 
 	return nil
@@ -207,13 +216,18 @@ func CreateServer(c *fiber.Ctx) error {
 	serverId := utils.GenerateNanoid()
 
 	server.ServerId = serverId
-	server.Banner = "https://d2b2cq6cks3j1i.cloudfront.net/server_banner/banner_" + serverId + "_v1.webp"
+	// A new server has no media, the client uploads the first banner and
+	// icon through PresignServerMedia, which hands out version 1.
+	server.Banner = ""
+	server.BannerVersion = 0
+	server.Icon = ""
+	server.IconVersion = 0
 	server.Owner = userUUID
 
 	t := time.Now()
 
-	queryCreateServer := "INSERT INTO servers (server_id, created_at, banner, description, name, owner, status) VALUES (?, ?, ?, ?, ?, ?, ?)"
-	if err := db.Query(queryCreateServer, server.ServerId, t, server.Banner, server.Description, server.Name, server.Owner, server.Status).Exec(); err != nil {
+	queryCreateServer := "INSERT INTO servers (server_id, created_at, banner, banner_version, description, icon, icon_version, name, owner, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if err := db.Query(queryCreateServer, server.ServerId, t, server.Banner, server.BannerVersion, server.Description, server.Icon, server.IconVersion, server.Name, server.Owner, server.Status).Exec(); err != nil {
 		log.Error(err)
 		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the server"})
 	} else {
@@ -303,15 +317,12 @@ func RollbackQueries(db *gocql.Session) {
 // DELETE SERVER
 
 func DeleteServer(c *fiber.Ctx) error {
-	db := database.DB
 	type BodyRequest struct {
-		Id string `json:"server_id"`
+		Id            string `json:"server_id"`
+		TwoFactorCode string `json:"two_factor_code"`
 	}
 
 	var serverId BodyRequest
-	var server models.Server
-	var channels []models.Channel
-	var users []gocql.UUID
 
 	err := c.BodyParser(&serverId)
 	if err != nil {
@@ -319,78 +330,104 @@ func DeleteServer(c *fiber.Ctx) error {
 		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the server's informations"})
 	}
 
-	queryGetServer := "SELECT * FROM servers WHERE server_id = ?"
-	if err := db.Query(queryGetServer, serverId.Id).Scan(&server.ServerId, &server.CreatedAt, &server.Banner, &server.Description, &server.Name, &server.Owner, &server.Status); err != nil {
+	server, err := utils.GetServerInformations(serverId.Id)
+	if err != nil {
 		log.Error(err)
 		return c.Status(404).JSON(fiber.Map{"error": "The server you're trying to delete does not exist."})
 	}
 
+	if !canManageServer(server, c.Locals("user_id").(string)) {
+		return c.Status(403).JSON(fiber.Map{"error": "You can't delete this server"})
+	}
+
+	if status, err := checkSecondFactor(server.Owner, serverId.TwoFactorCode); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if status, err := deleteServer(serverId.Id); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return nil
+}
+
+// deleteServer deletes the server with its channels and memberships, and
+// lets the members know.
+func deleteServer(serverId string) (int, error) {
+	db := database.DB
+	var channels []models.Channel
+	var users []gocql.UUID
+
 	queryGetChannels := "SELECT * FROM channels WHERE server_id = ?"
-	scanner := db.Query(queryGetChannels, serverId.Id).Iter().Scanner()
+	scanner := db.Query(queryGetChannels, serverId).Iter().Scanner()
 	for scanner.Next() {
 		var channel models.Channel
 		err := scanner.Scan(&channel.ServerId, &channel.ChannelId, &channel.Category, &channel.Name, &channel.ParentId, &channel.ParentPosition, &channel.Position, &channel.Status, &channel.Type)
 		if err != nil {
 			log.Error(err)
-			return c.Status(404).JSON(fiber.Map{"error": "Can't find any channels in this server."})
+			return 404, fmt.Errorf("Can't find any channels in this server.")
 		}
 		channels = append(channels, channel)
 	}
 
 	queryDeleteServer := "DELETE FROM servers WHERE server_id = ?"
-	if err := db.Query(queryDeleteServer, serverId.Id).Exec(); err != nil {
+	if err := db.Query(queryDeleteServer, serverId).Exec(); err != nil {
+		log.Error(err)
+		return 500, fmt.Errorf("Couldn't delete the server")
+	}
+
+	queryUnlistServer := "DELETE FROM public_servers WHERE server_id = ?"
+	if err := db.Query(queryUnlistServer, serverId).Exec(); err != nil {
 		log.Error(err)
-		return c.Status(500).JSON(fiber.Map{"error": "Couldn't delete the server"})
 	}
 
 	queryDeleteChannels := "DELETE FROM channels WHERE server_id = ?"
-	if err := db.Query(queryDeleteChannels, serverId.Id).Exec(); err != nil {
+	if err := db.Query(queryDeleteChannels, serverId).Exec(); err != nil {
 		log.Error(err)
-		return c.Status(500).JSON(fiber.Map{"error": "Couldn't delete a new channel"})
+		return 500, fmt.Errorf("Couldn't delete a new channel")
 	}
 
 	queryLeaveChannel := "DELETE FROM channel_to_users WHERE channel_id = ?"
 	for _, channel := range channels {
 		if err := db.Query(queryLeaveChannel, channel.ChannelId).Exec(); err != nil {
 			log.Error(err)
-			return c.Status(500).JSON(fiber.Map{"error": "An error occured while leaving the server"})
+			return 500, fmt.Errorf("An error occured while leaving the server")
 		}
 	}
 
 	queryGetUsersOfServer := "SELECT users FROM server_to_users WHERE server_id = ?"
-	if err := db.Query(queryGetUsersOfServer, serverId.Id).Scan(&users); err != nil {
+	if err := db.Query(queryGetUsersOfServer, serverId).Scan(&users); err != nil {
 		log.Error(err)
-		return c.Status(500).JSON(fiber.Map{"error": "An error occured while leaving the server"})
+		return 500, fmt.Errorf("An error occured while leaving the server")
 	}
 
 	queryDeleteServerListOfUsers := "DELETE FROM server_to_users WHERE server_id = ?"
-	if err := db.Query(queryDeleteServerListOfUsers, serverId.Id).Exec(); err != nil {
+	if err := db.Query(queryDeleteServerListOfUsers, serverId).Exec(); err != nil {
 		log.Error(err)
-		return c.Status(500).JSON(fiber.Map{"error": "Couldn't delete the list of users related to this server."})
+		return 500, fmt.Errorf("Couldn't delete the list of users related to this server.")
 	}
 
 	queryDeleteServerFromUsersList := "UPDATE user_to_servers SET servers = servers - ? WHERE user_id = ?"
 	queryDeleteUserState := "DELETE FROM user_to_server_state WHERE user_id = ? AND server_id = ?"
 	for _, userId := range users {
-		if err := db.Query(queryDeleteServerFromUsersList, []string{serverId.Id}, userId).Exec(); err != nil {
+		if err := db.Query(queryDeleteServerFromUsersList, []string{serverId}, userId).Exec(); err != nil {
 			log.Error(err)
-			return c.Status(500).JSON(fiber.Map{"error": "Can't delete user from server."})
+			return 500, fmt.Errorf("Can't delete user from server.")
 		}
 
-		if err := db.Query(queryDeleteUserState, userId, serverId.Id).Exec(); err != nil {
+		if err := db.Query(queryDeleteUserState, userId, serverId).Exec(); err != nil {
 			log.Error(err)
-			return c.Status(500).JSON(fiber.Map{"error": "An error occured while leaving the server"})
+			return 500, fmt.Errorf("An error occured while leaving the server")
 		}
 	}
 
-	broadcastServerChanges(users, Options{ServerId: &serverId.Id}, "server_deletion")
+	broadcastServerChanges(users, Options{ServerId: &serverId}, "server_deletion")
 
-	return nil
+	return 200, nil
 }
 
 func LeaveServer(c *fiber.Ctx) error {
 	userId := c.Locals("user_id").(string)
-	db := database.DB
 
 	type BodyRequest struct {
 		Id string `json:"server_id"`
@@ -404,7 +441,16 @@ func LeaveServer(c *fiber.Ctx) error {
 		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the server's informations"})
 	}
 
-	serverId := server.Id
+	if status, err := leaveServer(userId, server.Id); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return nil
+}
+
+// leaveServer removes the user from the server and its channels.
+func leaveServer(userId string, serverId string) (int, error) {
+	db := database.DB
 
 	var channels []models.Channel
 	queryGetChannels := "SELECT * FROM channels WHERE server_id = ?"
@@ -414,11 +460,10 @@ func LeaveServer(c *fiber.Ctx) error {
 		err := scanner.Scan(&channel.ServerId, &channel.ChannelId, &channel.Category, &channel.Name, &channel.ParentId, &channel.ParentPosition, &channel.Position, &channel.Status, &channel.Type)
 		if err != nil {
 			log.Error(err)
-			return c.Status(404).JSON(fiber.Map{"error": "Can't find any channels in this server."})
+			return 404, fmt.Errorf("Can't find any channels in this server.")
 		}
 		channels = append(channels, channel)
 	}
-	fmt.Println(channels)
 
 	if err := scanner.Err(); err != nil {
 		log.Error(err)
@@ -428,7 +473,7 @@ func LeaveServer(c *fiber.Ctx) error {
 	for _, channel := range channels {
 		if err := db.Query(queryLeaveChannel, channel.ChannelId, userId).Exec(); err != nil {
 			log.Error(err)
-			return c.Status(500).JSON(fiber.Map{"error": "An error occured while leaving the server"})
+			return 500, fmt.Errorf("An error occured while leaving the server")
 		}
 	}
 
@@ -436,30 +481,32 @@ func LeaveServer(c *fiber.Ctx) error {
 	queryGetUsersOfServer := "SELECT users FROM server_to_users WHERE server_id = ?"
 	if err := db.Query(queryGetUsersOfServer, serverId).Scan(&users); err != nil {
 		log.Error(err)
-		return c.Status(500).JSON(fiber.Map{"error": "An error occured while leaving the server"})
+		return 500, fmt.Errorf("An error occured while leaving the server")
 	}
 
 	queryLeaveServer := "UPDATE server_to_users SET users = users - ? WHERE server_id = ?"
 	if err := db.Query(queryLeaveServer, []string{userId}, serverId).Exec(); err != nil {
 		log.Error(err)
-		return c.Status(500).JSON(fiber.Map{"error": "An error occured while leaving the server"})
+		return 500, fmt.Errorf("An error occured while leaving the server")
 	}
 
 	queryDeleteServerFromUsersList := "UPDATE user_to_servers SET servers = servers - ? WHERE user_id = ?"
 	if err := db.Query(queryDeleteServerFromUsersList, []string{serverId}, userId).Exec(); err != nil {
 		log.Error(err)
-		return c.Status(500).JSON(fiber.Map{"error": "An error occured while leaving the server"})
+		return 500, fmt.Errorf("An error occured while leaving the server")
 	}
 
 	queryDeleteUserState := "DELETE FROM user_to_server_state WHERE user_id = ? AND server_id = ?"
 	if err := db.Query(queryDeleteUserState, userId, serverId).Exec(); err != nil {
 		log.Error(err)
-		return c.Status(500).JSON(fiber.Map{"error": "An error occured while leaving the server"})
+		return 500, fmt.Errorf("An error occured while leaving the server")
 	}
 
+	recordAudit(serverId, userId, auditActionMemberLeave, "user", userId, nil, nil)
+	refreshServerListing(serverId)
 	broadcastServerChanges(users, Options{ServerId: &serverId}, "user_leaving")
 
-	return nil
+	return 200, nil
 }
 
 func CreateChannel(c *fiber.Ctx) error {
@@ -485,6 +532,8 @@ func CreateChannel(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Couldn't create the new channel"})
 	}
 
+	recordAudit(newChannel.ServerId, c.Locals("user_id").(string), auditActionChannelCreate, "channel", newChannel.ChannelId, nil, newChannel)
+
 	var users []gocql.UUID
 	queryGetUsersOfServer := "SELECT users FROM server_to_users WHERE server_id = ?"
 	if err := db.Query(queryGetUsersOfServer, newChannel.ServerId).Scan(&users); err != nil {
@@ -520,12 +569,21 @@ func DeleteChannel(c *fiber.Ctx) error {
 		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the server's informations"})
 	}
 
+	var channel models.Channel
+	queryGetChannel := "SELECT * FROM channels WHERE server_id = ? AND channel_id = ?"
+	if err := db.Query(queryGetChannel, body.ServerId, body.ChannelId).Scan(&channel.ServerId, &channel.ChannelId, &channel.Category, &channel.Name, &channel.ParentId, &channel.ParentPosition, &channel.Position, &channel.Status, &channel.Type); err != nil {
+		log.Error(err)
+		return c.Status(404).JSON(fiber.Map{"error": "Channel not found"})
+	}
+
 	queryDeleteChannel := "DELETE FROM channels WHERE server_id = ? AND channel_id = ?"
 	if err := db.Query(queryDeleteChannel, body.ServerId, body.ChannelId).Exec(); err != nil {
 		log.Error(err)
 		return c.Status(500).JSON(fiber.Map{"error": "Couldn't delete the channel"})
 	}
 
+	recordAudit(body.ServerId, c.Locals("user_id").(string), auditActionChannelDelete, "channel", body.ChannelId, channel, nil)
+
 	queryDeleteMessages := "DELETE FROM messages WHERE channel_id = ?"
 	if err := db.Query(queryDeleteMessages, body.ChannelId).Exec(); err != nil {
 		log.Error(err)
@@ -550,18 +608,21 @@ func DeleteChannel(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Couldn't delete the channel"})
 	}
 
+	deleteReadStates(db, users, body.ChannelId)
+
 	broadcastServerChanges(users, Options{ServerId: &body.ServerId, ChannelId: &body.ChannelId, Group: &body.Group}, "channel_deletion")
 
 	return nil
 }
 
 type Options struct {
-	ServerId  *string
-	UserId    *string
-	ChannelId *string
-	Group     *string
-	Channel   *models.Channel
-	Server    *models.Server
+	ServerId      *string
+	UserId        *string
+	ChannelId     *string
+	Group         *string
+	Channel       *models.Channel
+	Server        *models.Server
+	DirectMessage *models.DirectMessage
 }
 
 func broadcastServerChanges(users []gocql.UUID, opts Options, typeOfMessage string) {
@@ -611,24 +672,43 @@ func broadcastServerChanges(users []gocql.UUID, opts Options, typeOfMessage stri
 			},
 		}
 		data, err = proto.Marshal(messageToSend)
+	case "group_dm_update":
+		messageToSend := &protobuf.ServerMessage{
+			Type: typeOfMessage,
+			Payload: &protobuf.ServerMessage_DirectMessage{
+				DirectMessage: directMessageToProtobuf(*opts.DirectMessage),
+			},
+		}
+		data, err = proto.Marshal(messageToSend)
+	case "group_dm_removal":
+		messageToSend := &protobuf.ServerMessage{
+			Type: typeOfMessage,
+			Payload: &protobuf.ServerMessage_ChannelDeletion{
+				ChannelDeletion: &protobuf.ChannelDeletion{
+					ChannelId: *opts.ChannelId,
+				},
+			},
+		}
+		data, err = proto.Marshal(messageToSend)
 	case "server_join", "server_creation":
 		messageToSend := &protobuf.ServerMessage{
 			Type: typeOfMessage,
 			Payload: &protobuf.ServerMessage_ServerJoin{
 				ServerJoin: &protobuf.ServerJoin{
 					UserId: *opts.UserId,
-					Server: &protobuf.Server{
-						ServerId:    *&opts.Server.ServerId,
-						Banner:      opts.Server.Banner,
-						Description: opts.Server.Description,
-						Name:        opts.Server.Name,
-						Owner:       opts.Server.Owner.String(),
-						Status:      opts.Server.Status,
-					},
+					Server: serverToProtobuf(*opts.Server),
 				},
 			},
 		}
 		data, err = proto.Marshal(messageToSend)
+	case "server_update":
+		messageToSend := &protobuf.ServerMessage{
+			Type: typeOfMessage,
+			Payload: &protobuf.ServerMessage_Server{
+				Server: serverToProtobuf(*opts.Server),
+			},
+		}
+		data, err = proto.Marshal(messageToSend)
 	}
 
 	if err != nil {
@@ -636,8 +716,6 @@ func broadcastServerChanges(users []gocql.UUID, opts Options, typeOfMessage stri
 	}
 
 	for _, user_id := range users {
-		if conn := Connections[user_id]; conn != nil {
-			conn.WriteMessage(websocket.BinaryMessage, data)
-		}
+		sendToUser(user_id, data, nil)
 	}
 }
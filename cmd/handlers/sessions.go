@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/auth"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// A session is kept alive by its refresh token: the access token in the
+// "session" cookie is short-lived and exchanged, along with the refresh
+// token, for new ones at /user/refresh. Each refresh token can be used once,
+// presenting one again means it leaked and the whole session is revoked.
+// Tabs of the same browser refresh at the same time though, so for
+// refreshGraceWindow after its use a refresh token still gets the tokens it
+// was exchanged for.
+const (
+	accessTokenDuration     = 15 * time.Minute
+	refreshTokenDuration    = 30 * 24 * time.Hour
+	refreshGraceWindow      = 30 * time.Second
+	websocketSessionRevoked = 4001
+	websocketTokenExpired   = 4002
+)
+
+// Logout ends the session of the request and clears its cookie.
+func Logout(c *fiber.Ctx) error {
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't log out"})
+	}
+
+	sessionId, _ := c.Locals("session_id").(string)
+	if err := revokeSession(userId, sessionId); err != nil && err != gocql.ErrNotFound {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't log out"})
+	}
+
+	if refreshToken := c.Cookies("refresh"); refreshToken != "" {
+		queryDeleteRefreshToken := "DELETE FROM refresh_tokens WHERE token_hash = ?"
+		if err := database.DB.Query(queryDeleteRefreshToken, hashToken(refreshToken)).Exec(); err != nil {
+			log.Error(err)
+		}
+	}
+
+	clearSessionCookies(c)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RefreshSession exchanges the refresh token for a new access token and a new
+// refresh token, which also pushes back the expiry of the session. The access
+// token is returned too so a connected websocket can re-authenticate.
+func RefreshSession(c *fiber.Ctx) error {
+	db := database.DB
+
+	refreshToken := c.Cookies("refresh")
+	if refreshToken == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	tokenHash := hashToken(refreshToken)
+
+	var session models.Session
+	var used bool
+	var usedAt time.Time
+	queryRefreshToken := "SELECT session_id, user_id, used, used_at FROM refresh_tokens WHERE token_hash = ?"
+	if err := db.Query(queryRefreshToken, tokenHash).Scan(&session.SessionId, &session.UserId, &used, &usedAt); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		clearSessionCookies(c)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if !used {
+		queryUseRefreshToken := "UPDATE refresh_tokens USING TTL ? SET used = true, used_at = ? WHERE token_hash = ? IF used = false"
+		applied, err := db.Query(queryUseRefreshToken, int(refreshTokenDuration.Seconds()), time.Now(), tokenHash).MapScanCAS(map[string]interface{}{})
+		if err != nil {
+			log.Error(err)
+			return c.Status(500).JSON(fiber.Map{"error": "Couldn't refresh the session"})
+		}
+
+		// Another request has just used it.
+		if !applied {
+			used = true
+			usedAt = time.Now()
+		}
+	}
+
+	if used {
+		if time.Since(usedAt) > refreshGraceWindow {
+			log.Warnf("Refresh token reused for the session %s of %s, revoking it", session.SessionId, session.UserId)
+			if err := revokeSession(session.UserId, session.SessionId); err != nil && err != gocql.ErrNotFound {
+				log.Error(err)
+			}
+			clearSessionCookies(c)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		tokens, err := getRefreshSuccessor(tokenHash)
+		if err == gocql.ErrNotFound {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The session is being refreshed"})
+		} else if err != nil {
+			log.Error(err)
+			return c.Status(500).JSON(fiber.Map{"error": "Couldn't refresh the session"})
+		}
+
+		return c.JSON(setSessionCookies(c, tokens))
+	}
+
+	querySession := "SELECT timezone, user_agent, created_at FROM user_sessions WHERE user_id = ? AND session_id = ?"
+	if err := db.Query(querySession, session.UserId, session.SessionId).Scan(&session.Timezone, &session.UserAgent, &session.CreatedAt); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		clearSessionCookies(c)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	tokens, err := createSessionTokens(session)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't refresh the session"})
+	}
+
+	// The successor is kept in clear, for the grace window only.
+	queryInsertSuccessor := "INSERT INTO refresh_successors (token_hash, access_token, refresh_token, expires_at) VALUES (?, ?, ?, ?) USING TTL ?"
+	if err := db.Query(queryInsertSuccessor, tokenHash, tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresAt, int(refreshGraceWindow.Seconds())).Exec(); err != nil {
+		log.Error(err)
+	}
+
+	return c.JSON(setSessionCookies(c, tokens))
+}
+
+// getRefreshSuccessor returns the tokens a refresh token was exchanged for.
+// The request that used it may still be issuing them, it's waited for a
+// moment before giving up with gocql.ErrNotFound.
+func getRefreshSuccessor(tokenHash string) (sessionTokens, error) {
+	db := database.DB
+	var tokens sessionTokens
+
+	querySuccessor := "SELECT access_token, refresh_token, expires_at FROM refresh_successors WHERE token_hash = ?"
+	for attempt := 0; ; attempt++ {
+		err := db.Query(querySuccessor, tokenHash).Scan(&tokens.AccessToken, &tokens.RefreshToken, &tokens.ExpiresAt)
+		if err != gocql.ErrNotFound || attempt == 10 {
+			return tokens, err
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// GetSessions lists the sessions of the user, the most recently seen first.
+func GetSessions(c *fiber.Ctx) error {
+	db := database.DB
+	currentSessionId, _ := c.Locals("session_id").(string)
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the sessions"})
+	}
+
+	sessions := []models.Session{}
+	querySessions := "SELECT session_id, timezone, user_agent, created_at, last_seen FROM user_sessions WHERE user_id = ?"
+	scanner := db.Query(querySessions, userId).Iter().Scanner()
+	for scanner.Next() {
+		session := models.Session{UserId: userId}
+		if err := scanner.Scan(&session.SessionId, &session.Timezone, &session.UserAgent, &session.CreatedAt, &session.LastSeen); err != nil {
+			log.Error(err)
+			continue
+		}
+		session.Current = session.SessionId == currentSessionId
+		sessions = append(sessions, session)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't fetch the sessions"})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+
+	return c.JSON(sessions)
+}
+
+// RevokeSession ends one of the sessions of the user, its websockets are
+// closed right away.
+func RevokeSession(c *fiber.Ctx) error {
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't revoke the session"})
+	}
+
+	if err := revokeSession(userId, c.Params("sessionId")); err != nil {
+		if err == gocql.ErrNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "Session not found"})
+		}
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't revoke the session"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeOtherSessions ends every session of the user but the one of the
+// request.
+func RevokeOtherSessions(c *fiber.Ctx) error {
+	currentSessionId, _ := c.Locals("session_id").(string)
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't revoke the sessions"})
+	}
+
+	revoked, err := revokeAllSessions(userId, currentSessionId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't revoke the sessions"})
+	}
+
+	return c.JSON(fiber.Map{"revoked": revoked})
+}
+
+// revokeAllSessions revokes every session of the user but except, which can
+// be empty.
+func revokeAllSessions(userId gocql.UUID, except string) (int, error) {
+	db := database.DB
+
+	var sessionIds []string
+	var sessionId string
+	querySessions := "SELECT session_id FROM user_sessions WHERE user_id = ?"
+	iter := db.Query(querySessions, userId).Iter()
+	for iter.Scan(&sessionId) {
+		if sessionId != except {
+			sessionIds = append(sessionIds, sessionId)
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return 0, err
+	}
+
+	for _, sessionId := range sessionIds {
+		if err := revokeSession(userId, sessionId); err != nil && err != gocql.ErrNotFound {
+			return 0, err
+		}
+	}
+
+	return len(sessionIds), nil
+}
+
+// revokeSession deletes the session so its token is refused from now on and
+// disconnects its websockets.
+func revokeSession(userId gocql.UUID, sessionId string) error {
+	db := database.DB
+	var session models.Session
+
+	querySession := "SELECT timezone, user_agent FROM user_sessions WHERE user_id = ? AND session_id = ?"
+	if err := db.Query(querySession, userId, sessionId).Scan(&session.Timezone, &session.UserAgent); err != nil {
+		return err
+	}
+
+	queryDeleteSession := "DELETE FROM sessions WHERE session_id = ? AND user_id = ? AND timezone = ? AND user_agent = ?"
+	if err := db.Query(queryDeleteSession, sessionId, userId, session.Timezone, session.UserAgent).Exec(); err != nil {
+		return err
+	}
+
+	queryDeleteUserSession := "DELETE FROM user_sessions WHERE user_id = ? AND session_id = ?"
+	if err := db.Query(queryDeleteUserSession, userId, sessionId).Exec(); err != nil {
+		return err
+	}
+
+	auth.Sessions.Forget(sessionId)
+	disconnectSession(userId, sessionId)
+	return nil
+}
+
+// startSession logs the user in on this device.
+func startSession(c *fiber.Ctx, user models.User, timezone string, userAgent string) error {
+	if err := createSession(c, user, timezone, userAgent); err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Unable to log in the user."})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(user)
+}
+
+// createSession saves a new session for the user and sets its cookies.
+func createSession(c *fiber.Ctx, user models.User, timezone string, userAgent string) error {
+	db := database.DB
+	var session models.Session
+
+	session.SessionId = utils.GenerateNanoid()
+	session.UserId = user.Id
+	session.Timezone = timezone
+	session.UserAgent = userAgent
+	session.CreatedAt = time.Now()
+
+	queryInsertSession := "INSERT INTO sessions (session_id, user_id, timezone, user_agent) VALUES (?, ?, ?, ?) ;"
+	if err := db.Query(queryInsertSession, session.SessionId, session.UserId, session.Timezone, session.UserAgent).Exec(); err != nil {
+		return err
+	}
+
+	_, err := issueSessionTokens(c, session)
+	return err
+}
+
+// sessionTokens are what a client gets when its session starts or is
+// refreshed.
+type sessionTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// issueSessionTokens sets the cookies of a new access token and a new
+// refresh token for the session, and extends the session for as long as the
+// refresh token is valid.
+func issueSessionTokens(c *fiber.Ctx, session models.Session) (fiber.Map, error) {
+	tokens, err := createSessionTokens(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return setSessionCookies(c, tokens), nil
+}
+
+func createSessionTokens(session models.Session) (sessionTokens, error) {
+	db := database.DB
+	now := time.Now()
+
+	queryInsertUserSession := "INSERT INTO user_sessions (user_id, session_id, timezone, user_agent, created_at, last_seen) VALUES (?, ?, ?, ?, ?, ?) USING TTL ?"
+	if err := db.Query(queryInsertUserSession, session.UserId, session.SessionId, session.Timezone, session.UserAgent, session.CreatedAt, now, int(refreshTokenDuration.Seconds())).Exec(); err != nil {
+		return sessionTokens{}, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return sessionTokens{}, err
+	}
+
+	queryInsertRefreshToken := "INSERT INTO refresh_tokens (token_hash, session_id, user_id, used, created_at) VALUES (?, ?, ?, false, ?) USING TTL ?"
+	if err := db.Query(queryInsertRefreshToken, hashToken(refreshToken), session.SessionId, session.UserId, now, int(refreshTokenDuration.Seconds())).Exec(); err != nil {
+		return sessionTokens{}, err
+	}
+
+	expiresAt := now.Add(accessTokenDuration)
+	token := jwt.New(jwt.SigningMethodHS256)
+	claims := token.Claims.(jwt.MapClaims)
+	claims["session_id"] = session.SessionId
+	claims["user_id"] = session.UserId
+	claims["timezone"] = session.Timezone
+	claims["user_agent"] = session.UserAgent
+	claims["exp"] = expiresAt.Unix()
+
+	accessToken, err := token.SignedString([]byte(env.Variable("SECRET")))
+	if err != nil {
+		return sessionTokens{}, err
+	}
+
+	return sessionTokens{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresAt: expiresAt}, nil
+}
+
+func setSessionCookies(c *fiber.Ctx, tokens sessionTokens) fiber.Map {
+	expires := time.Now().Add(refreshTokenDuration)
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "session",
+		Value:    tokens.AccessToken,
+		Path:     "/",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "None",
+		Expires:  expires,
+	})
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh",
+		Value:    tokens.RefreshToken,
+		Path:     "/api/user",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "None",
+		Expires:  expires,
+	})
+
+	return fiber.Map{"access_token": tokens.AccessToken, "expires_at": tokens.ExpiresAt}
+}
+
+func clearSessionCookies(c *fiber.Ctx) {
+	for name, path := range map[string]string{"session": "/", "refresh": "/api/user"} {
+		c.Cookie(&fiber.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     path,
+			HTTPOnly: true,
+			Secure:   true,
+			SameSite: "None",
+			Expires:  time.Unix(0, 0),
+		})
+	}
+}
+
+func randomToken(size int) (string, error) {
+	secret := make([]byte, size)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashToken is how the tokens and codes handed to the users are stored,
+// they're random enough for a plain SHA-256.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// touchSession refreshes the last time the session was seen, keeping the TTL
+// it was created with.
+func touchSession(userId gocql.UUID, sessionId string) {
+	db := database.DB
+
+	var ttl int
+	queryTTL := "SELECT TTL(created_at) FROM user_sessions WHERE user_id = ? AND session_id = ?"
+	if err := db.Query(queryTTL, userId, sessionId).Scan(&ttl); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	queryTouchSession := "UPDATE user_sessions USING TTL ? SET last_seen = ? WHERE user_id = ? AND session_id = ?"
+	if err := db.Query(queryTouchSession, ttl, time.Now(), userId, sessionId).Exec(); err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,472 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/totp"
+	"github.com/gocql/gocql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	recoveryCodesCount     = 10
+	loginChallengeDuration = 5 * time.Minute
+	maxChallengeAttempts   = 5
+)
+
+type twoFactor struct {
+	Secret        string
+	Enabled       bool
+	LastCounter   int64
+	RecoveryCodes []string
+}
+
+// SetupTwoFactor starts the enrollment with a new secret, it's only enabled
+// once a first code is confirmed with EnableTwoFactor.
+func SetupTwoFactor(c *fiber.Ctx) error {
+	db := database.DB
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't set up the two-factor authentication"})
+	}
+
+	if enabled, err := twoFactorEnabled(userId); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't set up the two-factor authentication"})
+	} else if enabled {
+		return c.Status(409).JSON(fiber.Map{"error": "The two-factor authentication is already enabled"})
+	}
+
+	user, err := GetUserById(userId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't set up the two-factor authentication"})
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't set up the two-factor authentication"})
+	}
+
+	querySetup := "INSERT INTO user_two_factor (user_id, secret, enabled, last_counter) VALUES (?, ?, false, 0)"
+	if err := db.Query(querySetup, userId, secret).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't set up the two-factor authentication"})
+	}
+
+	issuer := env.Variable("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Bulles"
+	}
+
+	return c.JSON(fiber.Map{"secret": secret, "uri": totp.ProvisioningURI(issuer, user.Email, secret)})
+}
+
+// EnableTwoFactor confirms the enrollment with a code from the app and
+// returns the recovery codes, they're only shown this once.
+func EnableTwoFactor(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Code string `json:"code"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the code"})
+	}
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't enable the two-factor authentication"})
+	}
+
+	settings, err := getTwoFactor(userId)
+	if err == gocql.ErrNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": "The two-factor authentication hasn't been set up"})
+	} else if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't enable the two-factor authentication"})
+	}
+
+	if settings.Enabled {
+		return c.Status(409).JSON(fiber.Map{"error": "The two-factor authentication is already enabled"})
+	}
+
+	if ok, err := useTOTPCode(userId, settings, body.Code); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't enable the two-factor authentication"})
+	} else if !ok {
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid code"})
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't enable the two-factor authentication"})
+	}
+
+	queryEnable := "UPDATE user_two_factor SET enabled = true, recovery_codes = ? WHERE user_id = ?"
+	if err := db.Query(queryEnable, hashes, userId).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't enable the two-factor authentication"})
+	}
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// DisableTwoFactor needs the password and a second factor.
+func DisableTwoFactor(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Password      string `json:"password"`
+		TwoFactorCode string `json:"two_factor_code"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the request"})
+	}
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't disable the two-factor authentication"})
+	}
+
+	user, err := GetUserById(userId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't disable the two-factor authentication"})
+	}
+
+	if !checkHashedPassword(user.Password, body.Password) {
+		return c.Status(401).JSON(fiber.Map{"error": "Your password is invalid"})
+	}
+
+	if status, err := checkSecondFactor(userId, body.TwoFactorCode); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	queryDisable := "DELETE FROM user_two_factor WHERE user_id = ?"
+	if err := db.Query(queryDisable, userId).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't disable the two-factor authentication"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, the previous ones stop
+// working.
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		TwoFactorCode string `json:"two_factor_code"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error when parsing the code"})
+	}
+
+	userId, err := gocql.ParseUUID(c.Locals("user_id").(string))
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't generate the recovery codes"})
+	}
+
+	if enabled, err := twoFactorEnabled(userId); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't generate the recovery codes"})
+	} else if !enabled {
+		return c.Status(404).JSON(fiber.Map{"error": "The two-factor authentication isn't enabled"})
+	}
+
+	if status, err := checkSecondFactor(userId, body.TwoFactorCode); err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't generate the recovery codes"})
+	}
+
+	queryRecoveryCodes := "UPDATE user_two_factor SET recovery_codes = ? WHERE user_id = ?"
+	if err := db.Query(queryRecoveryCodes, hashes, userId).Exec(); err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Couldn't generate the recovery codes"})
+	}
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// LoginTwoFactor is the second step of the login for the users with the
+// two-factor authentication, it trades the challenge returned by Login and a
+// code for the session.
+func LoginTwoFactor(c *fiber.Ctx) error {
+	db := database.DB
+	type BodyRequest struct {
+		Challenge     string `json:"challenge"`
+		TwoFactorCode string `json:"two_factor_code"`
+	}
+
+	var body BodyRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Errorf("Error when parsing the body: %v", err)
+		return c.Status(422).JSON(fiber.Map{"error": "Error on login request"})
+	}
+
+	challengeHash := hashToken(body.Challenge)
+
+	var userId gocql.UUID
+	var timezone, userAgent string
+	var attempts, ttl int
+	queryChallenge := "SELECT user_id, timezone, user_agent, attempts, TTL(user_id) FROM login_challenges WHERE challenge_hash = ?"
+	if err := db.Query(queryChallenge, challengeHash).Scan(&userId, &timezone, &userAgent, &attempts, &ttl); err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return c.Status(401).JSON(fiber.Map{"error": "Your login attempt expired, log in again"})
+	}
+
+	// The attempt is counted before the code is checked, so guesses sent in
+	// parallel can't all be checked against the same count.
+	allowed, err := countChallengeAttempt(challengeHash, attempts, ttl)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Unable to log in the user."})
+	}
+
+	if !allowed {
+		queryDeleteChallenge := "DELETE FROM login_challenges WHERE challenge_hash = ?"
+		if err := db.Query(queryDeleteChallenge, challengeHash).Exec(); err != nil {
+			log.Error(err)
+		}
+		return c.Status(401).JSON(fiber.Map{"error": "Too many invalid codes, log in again"})
+	}
+
+	settings, err := getTwoFactor(userId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Unable to log in the user."})
+	}
+
+	ok, err := verifySecondFactor(userId, settings, body.TwoFactorCode)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Unable to log in the user."})
+	}
+
+	if !ok {
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid code"})
+	}
+
+	// The challenge can only be traded once.
+	queryUseChallenge := "DELETE FROM login_challenges WHERE challenge_hash = ? IF EXISTS"
+	applied, err := db.Query(queryUseChallenge, challengeHash).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Unable to log in the user."})
+	}
+
+	if !applied {
+		return c.Status(401).JSON(fiber.Map{"error": "Your login attempt expired, log in again"})
+	}
+
+	user, err := GetUserById(userId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(500).JSON(fiber.Map{"error": "Unable to log in the user."})
+	}
+
+	return startSession(c, user, timezone, userAgent)
+}
+
+// countChallengeAttempt adds an attempt to the login challenge, false when
+// it already had all of them or it's gone. A concurrent attempt makes the
+// update fail, it's then retried from the count that attempt left.
+func countChallengeAttempt(challengeHash string, attempts int, ttl int) (bool, error) {
+	db := database.DB
+
+	queryAttempt := "UPDATE login_challenges USING TTL ? SET attempts = ? WHERE challenge_hash = ? IF attempts = ?"
+	for {
+		if attempts >= maxChallengeAttempts || ttl <= 0 {
+			return false, nil
+		}
+
+		previous := map[string]interface{}{}
+		applied, err := db.Query(queryAttempt, ttl, attempts+1, challengeHash, attempts).MapScanCAS(previous)
+		if err != nil {
+			return false, err
+		}
+
+		if applied {
+			return true, nil
+		}
+
+		current, ok := previous["attempts"].(int)
+		if !ok {
+			// Deleted in between.
+			return false, nil
+		}
+		attempts = current
+	}
+}
+
+// checkSecondFactor guards the sensitive actions: when the user has the
+// two-factor authentication, the request must carry a valid code or
+// recovery code.
+func checkSecondFactor(userId gocql.UUID, code string) (int, error) {
+	settings, err := getTwoFactor(userId)
+	if err == gocql.ErrNotFound {
+		return 200, nil
+	} else if err != nil {
+		log.Error(err)
+		return 500, fmt.Errorf("Couldn't check the two-factor authentication")
+	}
+
+	if !settings.Enabled {
+		return 200, nil
+	}
+
+	if code == "" {
+		return 403, fmt.Errorf("A two-factor authentication code is required")
+	}
+
+	ok, err := verifySecondFactor(userId, settings, code)
+	if err != nil {
+		log.Error(err)
+		return 500, fmt.Errorf("Couldn't check the two-factor authentication")
+	}
+
+	if !ok {
+		return 403, fmt.Errorf("Invalid two-factor authentication code")
+	}
+
+	return 200, nil
+}
+
+func twoFactorEnabled(userId gocql.UUID) (bool, error) {
+	settings, err := getTwoFactor(userId)
+	if err == gocql.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return settings.Enabled, nil
+}
+
+func getTwoFactor(userId gocql.UUID) (twoFactor, error) {
+	db := database.DB
+	var settings twoFactor
+
+	queryTwoFactor := "SELECT secret, enabled, last_counter, recovery_codes FROM user_two_factor WHERE user_id = ?"
+	err := db.Query(queryTwoFactor, userId).Scan(&settings.Secret, &settings.Enabled, &settings.LastCounter, &settings.RecoveryCodes)
+	return settings, err
+}
+
+// createLoginChallenge returns the token standing for a login whose password
+// has been checked but which still waits for the second factor.
+func createLoginChallenge(userId gocql.UUID, timezone string, userAgent string) (string, error) {
+	db := database.DB
+
+	challenge, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	queryChallenge := "INSERT INTO login_challenges (challenge_hash, user_id, timezone, user_agent, attempts) VALUES (?, ?, ?, ?, 0) USING TTL ?"
+	if err := db.Query(queryChallenge, hashToken(challenge), userId, timezone, userAgent, int(loginChallengeDuration.Seconds())).Exec(); err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// verifySecondFactor accepts a code from the app or one of the recovery
+// codes, which is then used up.
+func verifySecondFactor(userId gocql.UUID, settings twoFactor, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return useTOTPCode(userId, settings, code)
+	}
+
+	return useRecoveryCode(userId, settings, code)
+}
+
+// useTOTPCode refuses the codes of a step already used, so a code seen by
+// someone else can't be replayed.
+func useTOTPCode(userId gocql.UUID, settings twoFactor, code string) (bool, error) {
+	db := database.DB
+
+	counter, ok := totp.Validate(settings.Secret, code, time.Now())
+	if !ok || counter <= settings.LastCounter {
+		return false, nil
+	}
+
+	queryUseCounter := "UPDATE user_two_factor SET last_counter = ? WHERE user_id = ? IF last_counter = ?"
+	return db.Query(queryUseCounter, counter, userId, settings.LastCounter).MapScanCAS(map[string]interface{}{})
+}
+
+func useRecoveryCode(userId gocql.UUID, settings twoFactor, code string) (bool, error) {
+	db := database.DB
+
+	hash := hashToken(normalizeRecoveryCode(code))
+	remaining := make([]string, 0, len(settings.RecoveryCodes))
+	found := false
+	for _, recoveryCode := range settings.RecoveryCodes {
+		if recoveryCode == hash {
+			found = true
+			continue
+		}
+		remaining = append(remaining, recoveryCode)
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	queryUseRecoveryCode := "UPDATE user_two_factor SET recovery_codes = ? WHERE user_id = ? IF recovery_codes = ?"
+	return db.Query(queryUseRecoveryCode, remaining, userId, settings.RecoveryCodes).MapScanCAS(map[string]interface{}{})
+}
+
+// generateRecoveryCodes returns the codes to show, formatted as
+// "xxxxx-xxxxx", and their hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		secret := make([]byte, 7)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(secret))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -3,16 +3,12 @@ package handlers
 import (
 	"errors"
 	"net/mail"
-	"time"
 
 	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
-	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
-	"github.com/Mind-thatsall/fiber-htmx/cmd/utils"
 	"github.com/gocql/gocql"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -42,10 +38,6 @@ func CreateUser(c *fiber.Ctx) error {
 
 	user.Id = ID
 	user.Email = addr.Address
-	errExist := checkIfUserExist(user.Username, user.Email, db)
-	if errExist != nil {
-		return c.Status(409).Send([]byte("User already exist"))
-	}
 
 	hash, err := hashPassword(user.Password)
 	if err != nil {
@@ -54,33 +46,37 @@ func CreateUser(c *fiber.Ctx) error {
 
 	user.Password = hash
 
-	q := db.Query("INSERT INTO users (id, username, email, password) VALUES (?, ?, ?, ?)", user.Id, user.Username, user.Email, user.Password)
-	if err := q.Exec(); err != nil {
-		log.Errorf("Error when creating the user", err)
+	// Claimed like in UpdateAccount, so two sign ups can't take the same
+	// username or email.
+	if ok, err := claimUserLookup("existing_username", user.Username, user.Id); err != nil {
+		log.Error(err)
+		return c.Status(500).Send([]byte("Couldn't create the user"))
+	} else if !ok {
+		return c.Status(409).Send([]byte("User already exist"))
 	}
 
-	return nil
-}
-
-func checkIfUserExist(username, email string, db *gocql.Session) error {
-	type ExistingUser struct {
-		Username string
-		Email    string
+	if ok, err := claimUserLookup("existing_email", user.Email, user.Id); err != nil || !ok {
+		releaseUserLookup("existing_username", user.Username, user.Id)
+		if err != nil {
+			log.Error(err)
+			return c.Status(500).Send([]byte("Couldn't create the user"))
+		}
+		return c.Status(409).Send([]byte("User already exist"))
 	}
 
-	var user ExistingUser
-
-	queryUsername := db.Query("SELECT * FROM existing_username WHERE username = ?", username)
-	if err := queryUsername.Scan(&user.Username); err != nil {
-
-		queryEmail := db.Query("SELECT * FROM existing_email WHERE email = ?", email)
-		if err := queryEmail.Scan(&user.Email); err != nil {
-			return nil
-		}
+	q := db.Query("INSERT INTO users (id, username, email, email_verified, password) VALUES (?, ?, ?, false, ?)", user.Id, user.Username, user.Email, user.Password)
+	if err := q.Exec(); err != nil {
+		releaseUserLookup("existing_username", user.Username, user.Id)
+		releaseUserLookup("existing_email", user.Email, user.Id)
+		log.Errorf("Error when creating the user: %v", err)
+		return c.Status(500).Send([]byte("Couldn't create the user"))
+	}
 
+	if err := sendVerificationEmail(*user); err != nil {
+		log.Errorf("Error when sending the verification email: %v", err)
 	}
 
-	return errors.New("User already Exist")
+	return nil
 }
 
 func getUserByEmail(email string) (models.User, error) {
@@ -105,8 +101,8 @@ func GetUserById(id interface{}) (models.User, error) {
 	db := database.DB
 	var user models.User
 
-	query := "SELECT * FROM users WHERE id = ?"
-	if err := db.Query(query, id).Scan(&user.Id, &user.About, &user.Avatar, &user.Banner, &user.DisplayName, &user.Email, &user.Password, &user.Username); err != nil {
+	query := "SELECT id, about, avatar, banner, bot, displayname, email, email_verified, password, username FROM users WHERE id = ?"
+	if err := db.Query(query, id).Scan(&user.Id, &user.About, &user.Avatar, &user.Banner, &user.Bot, &user.DisplayName, &user.Email, &user.EmailVerified, &user.Password, &user.Username); err != nil {
 		log.Error(err)
 		return user, errors.New("User not found")
 	}
@@ -138,43 +134,21 @@ func Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Your email or password is invalid"})
 	}
 
-	var session models.Session
-
-	session.SessionId = utils.GenerateNanoid()
-	session.UserId = userData.Id
-	session.Timezone = input.Timezone
-	session.UserAgent = input.UserAgent
-
-	db := database.DB
-
-	queryInsertSession := "INSERT INTO sessions (session_id, user_id, timezone, user_agent) VALUES (?, ?, ?, ?) ;"
-	if err := db.Query(queryInsertSession, session.SessionId, session.UserId, session.Timezone, session.UserAgent).Exec(); err != nil {
+	enabled, err := twoFactorEnabled(userData.Id)
+	if err != nil {
 		log.Error(err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unable to log in the user."})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Unable to log in the user."})
 	}
 
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["session_id"] = session.SessionId
-	claims["user_id"] = session.UserId
-	claims["timezone"] = session.Timezone
-	claims["user_agent"] = session.UserAgent
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix()
+	if enabled {
+		challenge, err := createLoginChallenge(userData.Id, input.Timezone, input.UserAgent)
+		if err != nil {
+			log.Error(err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Unable to log in the user."})
+		}
 
-	t, err := token.SignedString([]byte(env.Variable("SECRET")))
-	if err != nil {
-		return c.SendStatus(fiber.StatusInternalServerError)
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"two_factor_required": true, "challenge": challenge})
 	}
 
-	cookie := new(fiber.Cookie)
-	cookie.Name = "session"
-	cookie.Value = t
-	cookie.HTTPOnly = true
-	cookie.Expires = time.Now().Add(time.Hour * 72)
-	cookie.SameSite = "None"
-	cookie.Secure = true
-	cookie.Path = "/"
-
-	c.Cookie(cookie)
-	return c.Status(fiber.StatusOK).JSON(userData)
+	return startSession(c, userData, input.Timezone, input.UserAgent)
 }
@@ -4,24 +4,141 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"sync"
+	"time"
 
+	"github.com/Mind-thatsall/fiber-htmx/cmd/auth"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
-	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/middleware"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/models"
 	"github.com/Mind-thatsall/fiber-htmx/public/protobuf"
 	"github.com/gocql/gocql"
 	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
-	"github.com/golang-jwt/jwt/v5"
 	"google.golang.org/protobuf/proto"
 )
 
-var Connections = make(map[gocql.UUID]*websocket.Conn)
+// Connection is one websocket opened by a user, a user can have several of
+// them at once (one per tab or device).
+type Connection struct {
+	Conn      *websocket.Conn
+	SessionId string
+	mu        sync.Mutex
+	expiry    *time.Timer
+	bot       bool
+}
+
+// websocketReauthGrace is how long a websocket stays open once its access
+// token expired, for the client to refresh it and send a "reauth" message.
+const websocketReauthGrace = time.Minute
+
+// expireAt closes the websocket when the token it was authenticated with
+// expires, unless it re-authenticates before.
+func (conn *Connection) expireAt(expiresAt time.Time) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.expiry != nil {
+		conn.expiry.Stop()
+	}
+
+	conn.expiry = time.AfterFunc(time.Until(expiresAt)+websocketReauthGrace, func() {
+		conn.close(websocketTokenExpired, "Token expired")
+	})
+}
+
+// close sends a close frame with the code before closing the websocket, its
+// read loop then ends and removes it.
+func (conn *Connection) close(code int, reason string) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	conn.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	conn.Conn.Close()
+}
+
+// Send writes a binary message, writes on a websocket must not be concurrent.
+func (conn *Connection) Send(data []byte) error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.Conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+var Connections = make(map[gocql.UUID]map[*Connection]bool)
+var connectionsMutex sync.RWMutex
+
+func addConnection(userId gocql.UUID, conn *Connection) {
+	connectionsMutex.Lock()
+	defer connectionsMutex.Unlock()
+
+	if Connections[userId] == nil {
+		Connections[userId] = make(map[*Connection]bool)
+	}
+	Connections[userId][conn] = true
+}
+
+func removeConnection(userId gocql.UUID, conn *Connection) {
+	connectionsMutex.Lock()
+	defer connectionsMutex.Unlock()
+
+	delete(Connections[userId], conn)
+	if len(Connections[userId]) == 0 {
+		delete(Connections, userId)
+	}
+}
+
+// sendToUser sends data to every connection of the user, except the one
+// given (nil to reach all of them).
+func sendToUser(userId gocql.UUID, data []byte, except *Connection) {
+	connectionsMutex.RLock()
+	conns := make([]*Connection, 0, len(Connections[userId]))
+	for conn := range Connections[userId] {
+		if conn != except {
+			conns = append(conns, conn)
+		}
+	}
+	connectionsMutex.RUnlock()
+
+	for _, conn := range conns {
+		if err := conn.Send(data); err != nil {
+			log.Errorf("Error when sending on the websocket: %v", err)
+		}
+	}
+}
+
+// isConnected tells if the user has a websocket open on this backend.
+func isConnected(userId gocql.UUID) bool {
+	connectionsMutex.RLock()
+	defer connectionsMutex.RUnlock()
+
+	return len(Connections[userId]) > 0
+}
+
+// disconnectSession closes the websockets opened with the session.
+func disconnectSession(userId gocql.UUID, sessionId string) {
+	connectionsMutex.RLock()
+	conns := make([]*Connection, 0)
+	for conn := range Connections[userId] {
+		if conn.SessionId == sessionId {
+			conns = append(conns, conn)
+		}
+	}
+	connectionsMutex.RUnlock()
+
+	for _, conn := range conns {
+		conn.close(websocketSessionRevoked, "Session revoked")
+	}
+}
 
 type receivedMessage struct {
-	Type     string `json:"type"`
-	ServerId string `json:"server_id"`
-	Position string `json:"pos"`
+	Type      string `json:"type"`
+	ServerId  string `json:"server_id"`
+	Position  string `json:"pos"`
+	ChannelId string `json:"channel_id"`
+	MessageId string `json:"message_id"`
+	Token     string `json:"token"`
 }
 
 func Connect(c *websocket.Conn) {
@@ -32,19 +149,31 @@ func Connect(c *websocket.Conn) {
 		errWebsocket error
 	)
 
-	userId, errWebsocket := CheckConnectionWebsocket(env.Variable("SECRET"), c)
+	identity, errWebsocket := CheckConnectionWebsocket(c)
 	if errWebsocket != nil {
 		c.Close()
 		return
 	}
 
+	userUUID, _ := gocql.ParseUUID(identity.UserId)
+	conn := &Connection{Conn: c, SessionId: identity.SessionId, bot: identity.Bot}
+	addConnection(userUUID, conn)
+	// The token of a bot doesn't expire, it's closed when it's revoked.
+	if !identity.Bot {
+		touchSession(userUUID, identity.SessionId)
+		conn.expireAt(identity.ExpiresAt)
+	}
+
+	go deliverFiredReminders(userUUID)
+
 	defer func() {
+		if conn.expiry != nil {
+			conn.expiry.Stop()
+		}
+		removeConnection(userUUID, conn)
 		c.Close()
 	}()
 
-	userUUID, _ := gocql.ParseUUID(userId.(string))
-	Connections[userUUID] = c
-
 	for {
 		if _, msg, errWebsocket = c.ReadMessage(); errWebsocket != nil {
 			log.Info("read:", errWebsocket)
@@ -58,6 +187,11 @@ func Connect(c *websocket.Conn) {
 		}
 		log.Info("recv: ", newMsg)
 
+		if ok, retryAfter := websocketLimiter.Allow(userUUID.String()); !ok {
+			sendRateLimited(userUUID, conn, "websocket", retryAfter)
+			continue
+		}
+
 		if newMsg.Type == "initial" {
 			message, err := getInformations(userUUID, newMsg.Position)
 			if err != nil {
@@ -74,10 +208,10 @@ func Connect(c *websocket.Conn) {
 			if err != nil {
 				fmt.Println("Error when transforming the message into protobuf", err)
 			}
-			c.WriteMessage(websocket.BinaryMessage, data)
+			conn.Send(data)
 
 		} else if newMsg.Type == "change_server" {
-			channels, err := getServer(newMsg.ServerId)
+			channels, err := getServer(newMsg.ServerId, userUUID)
 			if err != nil {
 				log.Error(err)
 			}
@@ -87,6 +221,7 @@ func Connect(c *websocket.Conn) {
 				Payload: &protobuf.ServerMessage_ChangeServer{
 					ChangeServer: &protobuf.ChangeServer{
 						Server: channels,
+						Unread: serverUnreadToProtobuf(getServerUnread(newMsg.ServerId, userUUID)),
 					},
 				},
 			}
@@ -96,42 +231,54 @@ func Connect(c *websocket.Conn) {
 				fmt.Println("Error when transforming the message into protobuf", err)
 			}
 
-			c.WriteMessage(websocket.BinaryMessage, data)
+			conn.Send(data)
+		} else if newMsg.Type == "ack" {
+			if _, err := ackMessage(userUUID, newMsg.ChannelId, newMsg.MessageId, conn); err != nil {
+				log.Error(err)
+			}
+		} else if newMsg.Type == "reauth" && !conn.bot {
+			reauthenticate(userUUID, conn, newMsg.Token)
 		}
 	}
 }
 
-func CheckConnectionWebsocket(secretKey string, c *websocket.Conn) (interface{}, error) {
-	cookie := c.Cookies("session")
-	db := database.DB
-	var userId interface{}
+// reauthenticate lets a websocket carry on with the access token the client
+// got from a refresh, it must belong to the same session.
+func reauthenticate(userId gocql.UUID, conn *Connection, token string) {
+	identity, err := auth.Sessions.Authenticate(token)
+	if err != nil || identity.UserId != userId.String() || identity.SessionId != conn.SessionId {
+		conn.close(websocketTokenExpired, "Invalid token")
+		return
+	}
 
-	token, err := jwt.Parse(cookie, func(token *jwt.Token) (interface{}, error) {
-		// Validate the alg
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secretKey), nil
-	})
+	conn.expireAt(identity.ExpiresAt)
+	touchSession(userId, identity.SessionId)
 
+	data, err := proto.Marshal(&protobuf.ServerMessage{Type: "reauth"})
 	if err != nil {
-		return nil, fmt.Errorf("Wrong signature")
+		fmt.Println("Error when transforming the message into protobuf", err)
+		return
 	}
+	conn.Send(data)
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		var session models.Session
+// CheckConnectionWebsocket authenticates the user with the session cookie,
+// or the bot with its token when it opens the gateway.
+func CheckConnectionWebsocket(c *websocket.Conn) (auth.Identity, error) {
+	if token, ok := middleware.BotToken(c.Headers(fiber.HeaderAuthorization)); ok {
+		identity, err := auth.Sessions.AuthenticateBot(token)
+		if err != nil {
+			return identity, err
+		}
 
-		userId = claims["user_id"]
-		querySession := "SELECT * FROM sessions WHERE session_id = ? AND user_id = ? AND timezone = ? AND user_agent = ?"
-		if err := db.Query(querySession, claims["session_id"], claims["user_id"], claims["timezone"], claims["user_agent"]).Scan(&session.SessionId, &session.UserId, &session.Timezone, &session.UserAgent); err != nil {
-			log.Error(err)
-			return nil, fmt.Errorf("Session non existant")
+		if !identity.Allows(auth.ScopeGateway) {
+			return auth.Identity{}, errBotMissingGatewayScope
 		}
-	} else {
-		return nil, fmt.Errorf("Token have no claims")
+
+		return identity, nil
 	}
 
-	return userId, nil
+	return auth.Sessions.Authenticate(c.Cookies("session"))
 }
 
 func getInformations(userId gocql.UUID, pos string) (*protobuf.ServerMessage_InitialLoad, error) {
@@ -157,10 +304,10 @@ func getInformations(userId gocql.UUID, pos string) (*protobuf.ServerMessage_Ini
 		return nil, fmt.Errorf("Impossible to fetch servers")
 	}
 
-	queryGetServer := "SELECT server_id, banner, description, name, owner, status FROM servers WHERE server_id = ?"
+	queryGetServer := "SELECT server_id, banner, description, icon, name, owner, status FROM servers WHERE server_id = ?"
 	for _, id := range serversId {
 		var server protobuf.Server
-		err := db.Query(queryGetServer, id).Scan(&server.ServerId, &server.Banner, &server.Description, &server.Name, &server.Owner, &server.Status)
+		err := db.Query(queryGetServer, id).Scan(&server.ServerId, &server.Banner, &server.Description, &server.Icon, &server.Name, &server.Owner, &server.Status)
 		if err != nil {
 			log.Error(err)
 			return nil, fmt.Errorf("Impossible to the server")
@@ -180,11 +327,42 @@ func getInformations(userId gocql.UUID, pos string) (*protobuf.ServerMessage_Ini
 		serverStates.Map[serverID] = channelID
 	}
 
+	dms, err := getDirectMessages(userId)
+	if err != nil {
+		return nil, fmt.Errorf("Impossible to fetch direct messages")
+	}
+
+	var directMessages []*protobuf.DirectMessage
+	var readStates []*protobuf.ReadState
+	for _, dm := range dms {
+		directMessages = append(directMessages, directMessageToProtobuf(dm))
+		readStates = append(readStates, readStateToProtobuf(getReadState(userId, "", dm.ChannelId)))
+	}
+
+	var serverUnreads []*protobuf.ServerUnread
+	for _, id := range serversId {
+		serverUnreads = append(serverUnreads, serverUnreadToProtobuf(getServerUnread(id, userId)))
+	}
+
+	settings, err := getNotificationSettings(userId)
+	if err != nil {
+		return nil, fmt.Errorf("Impossible to fetch notification settings")
+	}
+
+	var notificationSettings []*protobuf.NotificationSettings
+	for _, setting := range settings {
+		notificationSettings = append(notificationSettings, notificationSettingsToProtobuf(setting))
+	}
+
 	message.InitialLoad.User = &user
 	message.InitialLoad.Servers = servers
 	message.InitialLoad.Map = &serverStates
+	message.InitialLoad.DirectMessages = directMessages
+	message.InitialLoad.ReadStates = readStates
+	message.InitialLoad.ServerUnreads = serverUnreads
+	message.InitialLoad.NotificationSettings = notificationSettings
 	if pos != "me" {
-		channels, err := getServer(pos)
+		channels, err := getServer(pos, userId)
 		if err != nil {
 			return nil, err
 		}
@@ -195,6 +373,19 @@ func getInformations(userId gocql.UUID, pos string) (*protobuf.ServerMessage_Ini
 
 }
 
+func userToProtobuf(user models.User) *protobuf.User {
+	return &protobuf.User{
+		Id:          user.Id.String(),
+		Email:       user.Email,
+		Username:    user.Username,
+		About:       user.About,
+		Avatar:      user.Avatar,
+		Banner:      user.Banner,
+		DisplayName: user.DisplayName,
+		Bot:         user.Bot,
+	}
+}
+
 type ChannelTest struct {
 	ServerId       string `db:"server_id" json:"serverId"`
 	ChannelId      string `db:"channel_id" json:"channelId"`
@@ -212,7 +403,7 @@ type Category struct {
 	Channels []ChannelTest `json:"channels"`
 }
 
-func getServer(serverId string) (*protobuf.ServerInfos, error) {
+func getServer(serverId string, userId gocql.UUID) (*protobuf.ServerInfos, error) {
 	db := database.DB
 	var sortedChannels []*protobuf.Channel
 	message := &protobuf.ServerInfos{
@@ -230,6 +421,7 @@ func getServer(serverId string) (*protobuf.ServerInfos, error) {
 		}
 
 		sortedChannels = append(sortedChannels, channel)
+		message.ReadStates = append(message.ReadStates, readStateToProtobuf(getReadState(userId, serverId, channel.ChannelId)))
 	}
 
 	sort.Slice(sortedChannels, func(i, j int) bool {
@@ -262,11 +454,11 @@ func getServer(serverId string) (*protobuf.ServerInfos, error) {
 		return nil, fmt.Errorf("Impossible to fetch servers")
 	}
 
-	queryGetUser := "SELECT id, about, avatar, banner, displayname, email, username FROM users WHERE id = ?"
+	queryGetUser := "SELECT id, about, avatar, banner, bot, displayname, email, username FROM users WHERE id = ?"
 	for _, id := range usersID {
 		var user protobuf.User
 		var userUUID gocql.UUID
-		errUser := db.Query(queryGetUser, id).Scan(&userUUID, &user.About, &user.Avatar, &user.Banner, &user.DisplayName, &user.Email, &user.Username)
+		errUser := db.Query(queryGetUser, id).Scan(&userUUID, &user.About, &user.Avatar, &user.Banner, &user.Bot, &user.DisplayName, &user.Email, &user.Username)
 		if errUser != nil {
 			log.Error(errUser)
 			return nil, fmt.Errorf("Impossible to fetch servers")
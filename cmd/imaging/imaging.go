@@ -0,0 +1,188 @@
+// Package imaging validates the medias uploaded by the users and turns them
+// into resized webp variants. Decoding and validation are done with the
+// standard library, encoding relies on the cwebp binary from libwebp
+// (CWEBP_PATH, "cwebp" by default).
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+)
+
+const (
+	MaxBytes     = 8 << 20
+	MaxDimension = 4096
+)
+
+var (
+	ErrInvalidImage = errors.New("the file is not a supported image")
+	ErrTooLarge     = errors.New("the image is too large")
+)
+
+type Variant struct {
+	// Size is the width the variant was resized to, at most.
+	Size int
+	Data []byte
+}
+
+type Result struct {
+	Width    int
+	Height   int
+	Variants []Variant
+}
+
+// Largest returns the biggest variant, the one stored as the media itself.
+func (result Result) Largest() Variant {
+	return result.Variants[len(result.Variants)-1]
+}
+
+// Process checks data is a jpeg, png, gif or webp image within the limits
+// and encodes it as webp once for each size, keeping the aspect ratio and
+// never upscaling. The metadata, EXIF included, is dropped on the way.
+func Process(data []byte, sizes []int) (Result, error) {
+	if len(data) > MaxBytes {
+		return Result{}, ErrTooLarge
+	}
+
+	if len(sizes) == 0 {
+		return Result{}, fmt.Errorf("no size to generate")
+	}
+
+	width, height, isWebp, err := decodeConfig(data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if width <= 0 || height <= 0 {
+		return Result{}, ErrInvalidImage
+	}
+
+	if width > MaxDimension || height > MaxDimension {
+		return Result{}, ErrTooLarge
+	}
+
+	dir, err := os.MkdirTemp("", "imaging")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	if !isWebp {
+		// Re-encoding through image/png is what gets rid of the metadata for
+		// the formats cwebp can't read directly.
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Result{}, ErrInvalidImage
+		}
+
+		var buffer bytes.Buffer
+		if err := png.Encode(&buffer, img); err != nil {
+			return Result{}, err
+		}
+		data = buffer.Bytes()
+	}
+
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return Result{}, err
+	}
+
+	sorted := append([]int(nil), sizes...)
+	sort.Ints(sorted)
+
+	result := Result{Width: width, Height: height}
+	for _, size := range sorted {
+		output := filepath.Join(dir, strconv.Itoa(size)+".webp")
+		if err := encode(input, output, min(size, width)); err != nil {
+			return Result{}, err
+		}
+
+		variant, err := os.ReadFile(output)
+		if err != nil {
+			return Result{}, err
+		}
+		result.Variants = append(result.Variants, Variant{Size: size, Data: variant})
+	}
+
+	return result, nil
+}
+
+func encode(input string, output string, width int) error {
+	cwebp := env.Variable("CWEBP_PATH")
+	if cwebp == "" {
+		cwebp = "cwebp"
+	}
+
+	cmd := exec.Command(cwebp, "-quiet", "-metadata", "none", "-q", "85", "-resize", strconv.Itoa(width), "0", input, "-o", output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cwebp failed: %v: %s", err, out)
+	}
+
+	return nil
+}
+
+func decodeConfig(data []byte) (width int, height int, isWebp bool, err error) {
+	if width, height, ok := webpConfig(data); ok {
+		return width, height, true, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, false, ErrInvalidImage
+	}
+
+	return config.Width, config.Height, false, nil
+}
+
+// webpConfig reads the dimensions from the header of the first chunk of a
+// webp file, be it lossy (VP8), lossless (VP8L) or extended (VP8X).
+func webpConfig(data []byte) (int, int, bool) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+
+	le24 := func(b []byte) int {
+		return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	}
+
+	switch string(data[12:16]) {
+	case "VP8 ":
+		if data[23] != 0x9d || data[24] != 0x01 || data[25] != 0x2a {
+			return 0, 0, false
+		}
+		width := int(binary.LittleEndian.Uint16(data[26:28]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(data[28:30]) & 0x3fff)
+		return width, height, true
+	case "VP8L":
+		if data[20] != 0x2f {
+			return 0, 0, false
+		}
+		bits := binary.LittleEndian.Uint32(data[21:25])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, true
+	case "VP8X":
+		return le24(data[24:27]) + 1, le24(data[27:30]) + 1, true
+	}
+
+	return 0, 0, false
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
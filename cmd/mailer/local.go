@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// File writes every message to its own .eml file, to be opened with any mail
+// client.
+type File struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir string, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (mailer *File) Send(message Message) error {
+	if err := os.MkdirAll(mailer.dir, 0o755); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102-150405"), now.UnixNano())
+	return os.WriteFile(filepath.Join(mailer.dir, name), format(mailer.from, message, now), 0o644)
+}
+
+// Log only prints the messages.
+type Log struct{}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+func (mailer *Log) Send(message Message) error {
+	log.Infof("Mail to %s: %s\n%s", message.To, message.Subject, message.Text)
+	return nil
+}
@@ -0,0 +1,86 @@
+// Package mailer sends the emails of the backend (address verification,
+// password reset) through SMTP, or writes them to disk or to the logs when
+// developing locally.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+type Mailer interface {
+	Send(message Message) error
+}
+
+var Mail Mailer
+
+// InitMailer picks the implementation from MAIL_DRIVER: "smtp", "file" (one
+// .eml file per message in MAIL_DIR) or "log". The emails carry live reset
+// and verification links, so "log" is only the default with MODE=DEV, the
+// backend doesn't start without a driver otherwise.
+func InitMailer() {
+	from := env.Variable("MAIL_FROM")
+	if from == "" {
+		from = "Bulles <no-reply@localhost>"
+	}
+
+	driver := env.Variable("MAIL_DRIVER")
+	if driver == "" && env.Variable("MODE") == "DEV" {
+		driver = "log"
+	}
+
+	switch driver {
+	case "smtp":
+		Mail = NewSMTP(SMTPConfig{
+			Host:     env.Variable("SMTP_HOST"),
+			Port:     env.Variable("SMTP_PORT"),
+			Username: env.Variable("SMTP_USERNAME"),
+			Password: env.Variable("SMTP_PASSWORD"),
+			From:     from,
+		})
+	case "file":
+		dir := env.Variable("MAIL_DIR")
+		if dir == "" {
+			dir = "./mails"
+		}
+		Mail = NewFile(dir, from)
+	case "log":
+		Mail = NewLog()
+	case "":
+		log.Fatal("MAIL_DRIVER must be set outside of MODE=DEV")
+	default:
+		log.Fatalf("Unknown MAIL_DRIVER=%q", driver)
+	}
+}
+
+// format writes the message as sent over SMTP, with CRLF line endings.
+func format(from string, message Message, date time.Time) []byte {
+	var buffer bytes.Buffer
+
+	fmt.Fprintf(&buffer, "From: %s\r\n", from)
+	fmt.Fprintf(&buffer, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buffer.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buffer.WriteString("\r\n")
+
+	text := strings.ReplaceAll(message.Text, "\r\n", "\n")
+	buffer.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+	buffer.WriteString("\r\n")
+
+	return buffer.Bytes()
+}
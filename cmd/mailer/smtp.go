@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type SMTP struct {
+	config SMTPConfig
+}
+
+func NewSMTP(config SMTPConfig) *SMTP {
+	if config.Port == "" {
+		config.Port = "587"
+	}
+
+	return &SMTP{config: config}
+}
+
+// Send uses STARTTLS when the server offers it, authentication is only
+// attempted with a username.
+func (mailer *SMTP) Send(message Message) error {
+	if strings.ContainsAny(message.To, "\r\n") {
+		return fmt.Errorf("invalid recipient %q", message.To)
+	}
+
+	from, err := mail.ParseAddress(mailer.config.From)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if mailer.config.Username != "" {
+		auth = smtp.PlainAuth("", mailer.config.Username, mailer.config.Password, mailer.config.Host)
+	}
+
+	address := net.JoinHostPort(mailer.config.Host, mailer.config.Port)
+	return smtp.SendMail(address, auth, from.Address, []string{message.To}, format(mailer.config.From, message, time.Now()))
+}
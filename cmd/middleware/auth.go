@@ -1,49 +1,61 @@
 package middleware
 
 import (
-	"fmt"
+	"strings"
 
-	"github.com/Mind-thatsall/fiber-htmx/cmd/handlers"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/auth"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func JWTAuthMiddleware(secretKey string) fiber.Handler {
+// JWTAuthMiddleware lets in the users logged in with the session cookie.
+// Bots send "Authorization: Bot <token>" instead and are only let in on the
+// routes giving the scopes they need, the others are kept to humans.
+func JWTAuthMiddleware(scopes ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		cookie := c.Cookies("session")
+		if token, ok := BotToken(c.Get(fiber.HeaderAuthorization)); ok {
+			if len(scopes) == 0 {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Bots can't use this route"})
+			}
 
-		token, err := jwt.Parse(cookie, func(token *jwt.Token) (interface{}, error) {
-			// Validate the alg
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			identity, err := auth.Sessions.AuthenticateBot(token)
+			if err != nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+			}
+
+			for _, scope := range scopes {
+				if !identity.Allows(scope) {
+					return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "The bot is missing the scope " + scope})
+				}
 			}
-			return []byte(secretKey), nil
-		})
 
+			c.Locals("user_id", identity.UserId)
+			c.Locals("session_id", identity.SessionId)
+			c.Locals("bot", true)
+
+			return c.Next()
+		}
+
+		identity, err := auth.Sessions.Authenticate(c.Cookies("session"))
 		if err != nil {
 			log.Error(err)
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			userId := claims["user_id"]
-			if userId == nil {
-				// If there is no userId claim in the JWT token, return error
-				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-			} else {
-				_, err := handlers.GetUserById(userId)
-				if err != nil {
-					log.Error(err)
-					return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-				}
-				c.Locals("user_id", claims["user_id"])
-			}
-		} else {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-		}
+		c.Locals("user_id", identity.UserId)
+		c.Locals("session_id", identity.SessionId)
 
 		// If everything is ok, let the request pass
 		return c.Next()
 	}
 }
+
+// BotToken returns the token of an Authorization header of the Bot scheme.
+func BotToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bot") {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
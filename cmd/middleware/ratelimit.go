@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// IPRateLimit limits the requests made to the route from a single IP.
+func IPRateLimit(limit ratelimit.Limit) fiber.Handler {
+	limiter := ratelimit.New(limit)
+
+	return func(c *fiber.Ctx) error {
+		if ok, retryAfter := limiter.Allow(c.IP()); !ok {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many attempts, try again later", "retry_after": retryAfter.Milliseconds()})
+		}
+
+		return c.Next()
+	}
+}
@@ -17,14 +17,36 @@ type NewMessage struct {
 }
 
 type User struct {
-	Id          gocql.UUID `db:"id" json:"id"`
-	Email       string     `db:"email" json:"email"`
-	Username    string     `db:"username" json:"username"`
-	About       string     `db:"about" json:"about"`
-	Avatar      string     `db:"avatar" json:"avatar"`
-	Banner      string     `db:"banner" json:"banner"`
-	DisplayName string     `db:"displayname" json:"displayName"`
-	Password    string     `db:"password" json:"-"`
+	Id            gocql.UUID `db:"id" json:"id"`
+	Email         string     `db:"email" json:"email"`
+	EmailVerified bool       `db:"email_verified" json:"email_verified"`
+	Username      string     `db:"username" json:"username"`
+	About         string     `db:"about" json:"about"`
+	Avatar        string     `db:"avatar" json:"avatar"`
+	Banner        string     `db:"banner" json:"banner"`
+	DisplayName   string     `db:"displayname" json:"displayName"`
+	Password      string     `db:"password" json:"-"`
+	Bot           bool       `db:"bot" json:"bot"`
+}
+
+// Bot is a bot account, the user it belongs to manages it and its tokens.
+type Bot struct {
+	Id          gocql.UUID `db:"bot_id" json:"id"`
+	OwnerId     gocql.UUID `db:"owner_id" json:"ownerId"`
+	Username    string     `json:"username"`
+	DisplayName string     `json:"displayName"`
+	Avatar      string     `json:"avatar"`
+	Scopes      []string   `db:"scopes" json:"scopes"`
+	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// BotToken is an API token of a bot, the token itself is only sent when
+// it's created.
+type BotToken struct {
+	Id        string    `db:"token_id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+	Token     string    `json:"token,omitempty"`
 }
 
 type Subscriber struct {
@@ -34,34 +56,113 @@ type Subscriber struct {
 }
 
 type Session struct {
-	SessionId string     `db:"session_id"`
-	UserId    gocql.UUID `db:"user_id"`
-	Timezone  string     `db:"timezone"`
-	UserAgent string     `db:"user_agent"`
+	SessionId string     `db:"session_id" json:"id"`
+	UserId    gocql.UUID `db:"user_id" json:"-"`
+	Timezone  string     `db:"timezone" json:"timezone"`
+	UserAgent string     `db:"user_agent" json:"user_agent"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	LastSeen  time.Time  `db:"last_seen" json:"last_seen"`
+	Current   bool       `json:"current"`
 }
 
 type Message struct {
-	MessageId     gocql.UUID   `db:"message_id" json:"id"`
-	ChannelId     string       `db:"channel_id" json:"channel_id"`
-	ServerId      string       `db:"server_id" json:"server_id"`
+	MessageId       gocql.UUID   `db:"message_id" json:"id"`
+	ChannelId       string       `db:"channel_id" json:"channel_id"`
+	ServerId        string       `db:"server_id" json:"server_id"`
+	Content         string       `db:"content" json:"content"`
+	Mentions        []gocql.UUID `db:"mentions" json:"mentions"`
+	MentionsRoles   []string     `db:"mentions_roles" json:"mentionsRoles"`
+	MentionEveryone bool         `db:"mention_everyone" json:"mentionEveryone"`
+	AttachmentIds   []gocql.UUID `db:"attachments" json:"attachment_ids"`
+	Attachments     []Attachment `db:"-" json:"attachments"`
+	Embeds          []Embed      `db:"-" json:"embeds"`
+	User            User         `db:"-" json:"sender"`
+	UserId          gocql.UUID   `db:"user_id" json:"-"`
+	CreatedAt       time.Time    `db:"created_at" json:"createdAt"`
+}
+
+type AuditLogEntry struct {
+	Id         gocql.UUID             `db:"entry_id" json:"id"`
+	ServerId   string                 `db:"server_id" json:"serverId"`
+	ActorId    gocql.UUID             `db:"actor_id" json:"actorId"`
+	Action     string                 `db:"action" json:"action"`
+	TargetType string                 `db:"target_type" json:"targetType"`
+	TargetId   string                 `db:"target_id" json:"targetId"`
+	Changes    map[string]AuditChange `db:"changes" json:"changes"`
+	CreatedAt  time.Time              `db:"-" json:"createdAt"`
+}
+
+type AuditChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+type ScheduledItem struct {
+	Id            gocql.UUID   `db:"item_id" json:"id"`
+	UserId        gocql.UUID   `db:"user_id" json:"userId"`
+	Kind          string       `db:"kind" json:"kind"`
+	ChannelId     string       `db:"channel_id" json:"channelId"`
+	ServerId      string       `db:"server_id" json:"serverId"`
+	MessageId     gocql.UUID   `db:"message_id" json:"messageId"`
 	Content       string       `db:"content" json:"content"`
-	Mentions      []gocql.UUID `db:"mentions" json:"mentions"`
-	MentionsRoles []string     `db:"mentions_roles" json:"mentionsRoles"`
-	User          User         `db:"-" json:"sender"`
-	UserId        gocql.UUID   `db:"user_id" json:"-"`
+	AttachmentIds []gocql.UUID `db:"attachments" json:"attachmentIds"`
+	Note          string       `db:"note" json:"note"`
+	DueAt         time.Time    `db:"due_at" json:"dueAt"`
+	Status        string       `db:"status" json:"status"`
 	CreatedAt     time.Time    `db:"created_at" json:"createdAt"`
+	ClaimUntil    time.Time    `db:"claim_until" json:"-"`
 }
 
-type Server struct {
-	ServerId    string     `db:"server_id" json:"serverId"`
-	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
-	Banner      string     `db:"banner" json:"banner"`
-	Description string     `db:"description" json:"description"`
-	Name        string     `db:"name" json:"name"`
-	Owner       gocql.UUID `db:"owner" json:"owner"`
+type Embed struct {
+	Url         string `db:"url" json:"url"`
+	Type        string `db:"type" json:"type"`
+	Title       string `db:"title" json:"title"`
+	Description string `db:"description" json:"description"`
+	SiteName    string `db:"site_name" json:"siteName"`
+	Image       string `db:"image" json:"image"`
+}
+
+type Attachment struct {
+	Id          gocql.UUID `db:"attachment_id" json:"id"`
+	ChannelId   string     `db:"channel_id" json:"channelId"`
+	MessageId   gocql.UUID `db:"message_id" json:"-"`
+	UploaderId  gocql.UUID `db:"uploader_id" json:"-"`
+	Filename    string     `db:"filename" json:"filename"`
+	Size        int64      `db:"size" json:"size"`
+	ContentType string     `db:"content_type" json:"contentType"`
+	Width       int        `db:"width" json:"width"`
+	Height      int        `db:"height" json:"height"`
+	ObjectKey   string     `db:"object_key" json:"-"`
+	Url         string     `db:"url" json:"url"`
 	Status      string     `db:"status" json:"status"`
 }
 
+type Server struct {
+	ServerId      string     `db:"server_id" json:"serverId"`
+	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
+	Banner        string     `db:"banner" json:"banner"`
+	BannerVersion int        `db:"banner_version" json:"bannerVersion"`
+	Description   string     `db:"description" json:"description"`
+	Icon          string     `db:"icon" json:"icon"`
+	IconVersion   int        `db:"icon_version" json:"iconVersion"`
+	Name          string     `db:"name" json:"name"`
+	Owner         gocql.UUID `db:"owner" json:"owner"`
+	Status        string     `db:"status" json:"status"`
+	Category      string     `db:"category" json:"category"`
+	Tags          []string   `db:"tags" json:"tags"`
+}
+
+type DiscoverableServer struct {
+	ServerId    string   `json:"serverId"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Banner      string   `json:"banner"`
+	Icon        string   `json:"icon"`
+	Category    string   `json:"category"`
+	Tags        []string `json:"tags"`
+	MemberCount int      `json:"memberCount"`
+}
+
 type ServerState map[string]string
 
 type Channel struct {
@@ -82,6 +183,53 @@ type Category struct {
 	Name       string     `db:"name"`
 }
 
+type DirectMessage struct {
+	ChannelId    string     `db:"channel_id" json:"channelId"`
+	Type         string     `db:"type" json:"type"`
+	User         User       `db:"-" json:"user"`
+	PartnerId    gocql.UUID `db:"partner_id" json:"-"`
+	Name         string     `db:"name" json:"name"`
+	Icon         string     `db:"icon" json:"icon"`
+	Owner        gocql.UUID `db:"owner" json:"owner"`
+	Users        []User     `db:"-" json:"users"`
+	LastActivity time.Time  `db:"last_activity" json:"lastActivity"`
+}
+
+type ReadState struct {
+	UserId        gocql.UUID `db:"user_id" json:"-"`
+	ChannelId     string     `db:"channel_id" json:"channelId"`
+	LastMessageId gocql.UUID `db:"last_message_id" json:"lastMessageId"`
+	LastReadAt    time.Time  `db:"last_read_at" json:"lastReadAt"`
+	UnreadCount   int        `db:"-" json:"unreadCount"`
+	MentionCount  int        `db:"-" json:"mentionCount"`
+	Muted         bool       `db:"-" json:"muted"`
+}
+
+type ServerUnread struct {
+	ServerId     string `json:"serverId"`
+	UnreadCount  int    `json:"unreadCount"`
+	MentionCount int    `json:"mentionCount"`
+}
+
+type NotificationSettings struct {
+	TargetId         string    `db:"target_id" json:"targetId"`
+	TargetType       string    `db:"target_type" json:"targetType"`
+	Level            string    `db:"level" json:"level"`
+	MutedUntil       time.Time `db:"muted_until" json:"mutedUntil"`
+	SuppressEveryone bool      `db:"suppress_everyone" json:"suppressEveryone"`
+}
+
 type Invitation struct {
 	Id string `json:"invitation_id"`
 }
+
+type DataExport struct {
+	Id          gocql.UUID `db:"export_id" json:"id"`
+	UserId      gocql.UUID `db:"user_id" json:"-"`
+	Status      string     `db:"status" json:"status"`
+	ObjectKey   string     `db:"object_key" json:"-"`
+	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
+	CompletedAt time.Time  `db:"completed_at" json:"completedAt"`
+	URL         string     `db:"-" json:"url,omitempty"`
+	ExpiresAt   *time.Time `db:"-" json:"expiresAt,omitempty"`
+}
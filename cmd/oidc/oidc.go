@@ -0,0 +1,357 @@
+// Package oidc is a small OpenID Connect client for the "Sign in with ..."
+// buttons: authorization code flow with PKCE, ID tokens signed with RS256
+// and checked against the keys published by the provider.
+//
+// Providers are listed in OIDC_PROVIDERS (e.g. "google,gitlab") and each one
+// is configured with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_REDIRECT_URL. Any issuer serving
+// a discovery document works, a local mock provider included.
+package oidc
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown provider")
+	ErrInvalidIDToken  = errors.New("invalid ID token")
+)
+
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims are the claims of the ID token the backend cares about.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *discovery
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+func New(config Config) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+var Providers = make(map[string]*Provider)
+
+func InitProviders() {
+	for _, name := range strings.Split(env.Variable("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := Config{
+			Name:         name,
+			Issuer:       strings.TrimRight(env.Variable(prefix+"ISSUER"), "/"),
+			ClientID:     env.Variable(prefix + "CLIENT_ID"),
+			ClientSecret: env.Variable(prefix + "CLIENT_SECRET"),
+			RedirectURL:  env.Variable(prefix + "REDIRECT_URL"),
+		}
+
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			log.Errorf("The OIDC provider %s is missing its issuer, client id or redirect URL", name)
+			continue
+		}
+
+		Providers[name] = New(config)
+	}
+}
+
+func Get(name string) (*Provider, error) {
+	provider, ok := Providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	return provider, nil
+}
+
+// CodeChallenge is the S256 PKCE challenge of the verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthURL is where the user is sent to sign in with the provider.
+func (provider *Provider) AuthURL(state string, nonce string, verifier string) (string, error) {
+	document, err := provider.discover()
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", provider.config.ClientID)
+	values.Set("redirect_uri", provider.config.RedirectURL)
+	values.Set("scope", strings.Join(provider.config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", CodeChallenge(verifier))
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(document.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return document.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange trades the code of the callback for the ID token, and returns its
+// verified claims.
+func (provider *Provider) Exchange(code string, verifier string, nonce string) (Claims, error) {
+	document, err := provider.discover()
+	if err != nil {
+		return Claims{}, err
+	}
+
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", provider.config.RedirectURL)
+	values.Set("client_id", provider.config.ClientID)
+	values.Set("code_verifier", verifier)
+	if provider.config.ClientSecret != "" {
+		values.Set("client_secret", provider.config.ClientSecret)
+	}
+
+	response, err := provider.client.PostForm(document.TokenEndpoint, values)
+	if err != nil {
+		return Claims{}, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return Claims{}, fmt.Errorf("token endpoint answered %d: %s", response.StatusCode, body)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return Claims{}, err
+	}
+
+	return provider.VerifyIDToken(tokens.IDToken, nonce)
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// the ID token.
+func (provider *Provider) VerifyIDToken(idToken string, nonce string) (Claims, error) {
+	document, err := provider.discover()
+	if err != nil {
+		return Claims{}, err
+	}
+
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return provider.key(kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(document.Issuer),
+		jwt.WithAudience(provider.config.ClientID),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil || !token.Valid {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	if expires, err := claims.GetExpirationTime(); err != nil || expires == nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return Claims{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	result := Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+
+	if result.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	return result, nil
+}
+
+// discover fetches the discovery document once. The lock isn't held while
+// it's fetched, logins happening meanwhile may fetch it too.
+func (provider *Provider) discover() (*discovery, error) {
+	provider.mu.Lock()
+	cached := provider.discovery
+	provider.mu.Unlock()
+
+	if cached != nil {
+		return cached, nil
+	}
+
+	var document discovery
+	if err := provider.getJSON(provider.config.Issuer+"/.well-known/openid-configuration", &document); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimRight(document.Issuer, "/") != provider.config.Issuer {
+		return nil, fmt.Errorf("the discovery document is for %q, not %q", document.Issuer, provider.config.Issuer)
+	}
+
+	if document.AuthorizationEndpoint == "" || document.TokenEndpoint == "" || document.JWKSURI == "" {
+		return nil, fmt.Errorf("the discovery document of %s is incomplete", provider.config.Issuer)
+	}
+
+	provider.mu.Lock()
+	provider.discovery = &document
+	provider.mu.Unlock()
+
+	return &document, nil
+}
+
+// key returns the public key of the kid, the keys are fetched again when
+// it's unknown as the provider may have rotated them. They're fetched
+// without holding the lock, and swapped in once parsed.
+func (provider *Provider) key(kid string) (*rsa.PublicKey, error) {
+	document, err := provider.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	provider.mu.Lock()
+	if key, ok := provider.keys[kid]; ok {
+		provider.mu.Unlock()
+		return key, nil
+	}
+
+	// Not more than once a minute, a token with a made up kid mustn't make
+	// us hammer the provider.
+	if time.Since(provider.keysFetchedAt) < time.Minute {
+		provider.mu.Unlock()
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	provider.keysFetchedAt = time.Now()
+	provider.mu.Unlock()
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := provider.getJSON(document.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) > 4 {
+			continue
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	provider.mu.Lock()
+	provider.keys = keys
+	provider.mu.Unlock()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return key, nil
+}
+
+func (provider *Provider) getJSON(url string, value interface{}) error {
+	response, err := provider.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %d", url, response.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(value)
+}
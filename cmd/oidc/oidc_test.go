@@ -0,0 +1,297 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID = "client"
+	testKeyID    = "key-1"
+)
+
+// mockIssuer is a local OpenID provider serving the discovery document,
+// its keys and a token endpoint answering with the ID token it's given.
+type mockIssuer struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	idToken  string
+	verifier string
+	// When set, the JWKS endpoint signals on jwksEntered and waits for
+	// jwksRelease to be closed.
+	jwksEntered chan struct{}
+	jwksRelease chan struct{}
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &mockIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		if issuer.jwksRelease != nil {
+			issuer.jwksEntered <- struct{}{}
+			<-issuer.jwksRelease
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKeyID,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("code") != "code" || r.Form.Get("client_id") != testClientID {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		issuer.verifier = r.Form.Get("code_verifier")
+		json.NewEncoder(w).Encode(map[string]string{"id_token": issuer.idToken})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (issuer *mockIssuer) provider() *Provider {
+	return New(Config{
+		Name:        "mock",
+		Issuer:      issuer.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "https://localhost/api/user/oidc/mock/callback",
+	})
+}
+
+func (issuer *mockIssuer) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            issuer.server.URL,
+		"aud":            testClientID,
+		"sub":            "subject-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "User",
+		"nonce":          "nonce",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func (issuer *mockIssuer) sign(t *testing.T, claims jwt.MapClaims, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := provider.VerifyIDToken(issuer.sign(t, issuer.claims(), issuer.key), "nonce")
+	if err != nil {
+		t.Fatalf("valid token refused: %v", err)
+	}
+
+	want := Claims{Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "User"}
+	if claims != want {
+		t.Fatalf("got %+v, want %+v", claims, want)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+		key    *rsa.PrivateKey
+		nonce  string
+	}{
+		{name: "bad signature", key: otherKey},
+		{name: "wrong audience", modify: func(c jwt.MapClaims) { c["aud"] = "another-client" }},
+		{name: "wrong issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "no expiry", modify: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "nonce mismatch", nonce: "another-nonce"},
+		{name: "no subject", modify: func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := issuer.claims()
+			if test.modify != nil {
+				test.modify(claims)
+			}
+
+			key := issuer.key
+			if test.key != nil {
+				key = test.key
+			}
+
+			nonce := "nonce"
+			if test.nonce != "" {
+				nonce = test.nonce
+			}
+
+			_, err := provider.VerifyIDToken(issuer.sign(t, claims, key), nonce)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("got %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsOtherAlgorithms(t *testing.T) {
+	issuer := newMockIssuer(t)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, issuer.claims())
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := issuer.provider().VerifyIDToken(signed, "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("got %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestKeyFetchDoesNotBlockCachedKeys(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+
+	valid := issuer.sign(t, issuer.claims(), issuer.key)
+	if _, err := provider.VerifyIDToken(valid, "nonce"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next unknown kid fetches the keys again, from an endpoint that
+	// hangs until released.
+	provider.mu.Lock()
+	provider.keysFetchedAt = time.Time{}
+	provider.mu.Unlock()
+	issuer.jwksEntered = make(chan struct{})
+	issuer.jwksRelease = make(chan struct{})
+	defer close(issuer.jwksRelease)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, issuer.claims())
+	token.Header["kid"] = "rotated"
+	rotated, err := token.SignedString(issuer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go provider.VerifyIDToken(rotated, "nonce")
+	<-issuer.jwksEntered
+
+	done := make(chan error)
+	go func() {
+		_, err := provider.VerifyIDToken(valid, "nonce")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a token signed with a cached key waited for the keys being fetched")
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+
+	authURL, err := provider.AuthURL("state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := parsed.Query()
+	if parsed.Path != "/authorize" || query.Get("state") != "state" || query.Get("nonce") != "nonce" || query.Get("client_id") != testClientID {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") != CodeChallenge("verifier") {
+		t.Fatalf("missing PKCE challenge in %s", authURL)
+	}
+
+	issuer.idToken = issuer.sign(t, issuer.claims(), issuer.key)
+	claims, err := provider.Exchange("code", "verifier", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if issuer.verifier != "verifier" {
+		t.Fatalf("the token endpoint got the verifier %q", issuer.verifier)
+	}
+
+	if claims.Subject != "subject-1" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	if _, err := provider.Exchange("another-code", "verifier", "nonce"); err == nil {
+		t.Fatal("a code refused by the provider was accepted")
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636, appendix B.
+	if got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	issuer := newMockIssuer(t)
+
+	provider := New(Config{Issuer: issuer.server.URL + "/other", ClientID: testClientID})
+	if _, err := provider.AuthURL("state", "nonce", "verifier"); err == nil {
+		t.Fatal("a discovery document for another issuer was accepted")
+	}
+}
@@ -0,0 +1,167 @@
+// Package ratelimit provides in-memory token buckets and a duplicate message
+// detector. State is per process, which is enough with a single backend.
+package ratelimit
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Limit allows Burst events at once, refilled over Per.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// FromEnv reads a limit written as "<burst>/<duration>", e.g. "5/10s", from
+// the variable, the fallback is used when it's missing or invalid.
+func FromEnv(key string, fallback Limit) Limit {
+	value := env.Variable(key)
+	if value == "" {
+		return fallback
+	}
+
+	burst, per, found := strings.Cut(value, "/")
+	if !found {
+		log.Errorf("Invalid rate limit %s=%q", key, value)
+		return fallback
+	}
+
+	limit := Limit{}
+	var err error
+	if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst <= 0 {
+		log.Errorf("Invalid rate limit %s=%q", key, value)
+		return fallback
+	}
+
+	if limit.Per, err = time.ParseDuration(per); err != nil || limit.Per <= 0 {
+		log.Errorf("Invalid rate limit %s=%q", key, value)
+		return fallback
+	}
+
+	return limit
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type Limiter struct {
+	limit     Limit
+	rate      float64 // tokens per second
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func New(limit Limit) *Limiter {
+	return &Limiter{
+		limit:     limit,
+		rate:      float64(limit.Burst) / limit.Per.Seconds(),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket of key. When there's none left, it
+// returns how long to wait for the next one.
+func (limiter *Limiter) Allow(key string) (bool, time.Duration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := time.Now()
+	limiter.sweep(now)
+
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limiter.limit.Burst), last: now}
+		limiter.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limiter.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limiter.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limiter.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets the buckets that have been refilled, they're the same as new
+// ones.
+func (limiter *Limiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < time.Minute {
+		return
+	}
+	limiter.lastSweep = now
+
+	for key, b := range limiter.buckets {
+		if now.Sub(b.last) >= limiter.limit.Per {
+			delete(limiter.buckets, key)
+		}
+	}
+}
+
+type duplicate struct {
+	hash  uint64
+	count int
+	first time.Time
+}
+
+// Duplicates spots the same content being sent over and over: more than
+// Burst identical messages in a row within Per.
+type Duplicates struct {
+	limit     Limit
+	mu        sync.Mutex
+	entries   map[string]*duplicate
+	lastSweep time.Time
+}
+
+func NewDuplicates(limit Limit) *Duplicates {
+	return &Duplicates{limit: limit, entries: make(map[string]*duplicate), lastSweep: time.Now()}
+}
+
+// Seen records the content for key and tells whether it's one repeat too
+// many, along with when the same content will be accepted again.
+func (duplicates *Duplicates) Seen(key string, content string) (bool, time.Duration) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(strings.ToLower(strings.Join(strings.Fields(content), " "))))
+	hash := hasher.Sum64()
+
+	duplicates.mu.Lock()
+	defer duplicates.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(duplicates.lastSweep) >= time.Minute {
+		duplicates.lastSweep = now
+		for k, entry := range duplicates.entries {
+			if now.Sub(entry.first) >= duplicates.limit.Per {
+				delete(duplicates.entries, k)
+			}
+		}
+	}
+
+	entry, ok := duplicates.entries[key]
+	if !ok || entry.hash != hash || now.Sub(entry.first) >= duplicates.limit.Per {
+		duplicates.entries[key] = &duplicate{hash: hash, count: 1, first: now}
+		return false, 0
+	}
+
+	if entry.count >= duplicates.limit.Burst {
+		return true, duplicates.limit.Per - now.Sub(entry.first)
+	}
+
+	entry.count++
+	return false, 0
+}
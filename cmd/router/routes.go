@@ -1,38 +1,104 @@
 package router
 
 import (
-	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/auth"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/handlers"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/middleware"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/ratelimit"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/storage"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
 func SetupRoutes(app *fiber.App) {
-	var JWTMiddleware = middleware.JWTAuthMiddleware(env.Variable("SECRET"))
+	var JWTMiddleware = middleware.JWTAuthMiddleware()
+	// The routes bots can use, with the scope they need.
+	var MessagesReadMiddleware = middleware.JWTAuthMiddleware(auth.ScopeMessagesRead)
+	var MessagesWriteMiddleware = middleware.JWTAuthMiddleware(auth.ScopeMessagesWrite)
+	var ServersJoinMiddleware = middleware.JWTAuthMiddleware(auth.ScopeServersJoin)
 
 	app.Get("/ws/connect", websocket.New(handlers.Connect))
 
+	if local, ok := storage.Store.(*storage.Local); ok {
+		local.Register(app)
+	}
+
 	// Middleware
 	api := app.Group("/api", logger.New())
-	api.Post("/new_message/:serverId/:channelId", JWTMiddleware, handlers.NewMessage)
-	api.Post("/new_dm/:channelId", JWTMiddleware, handlers.NewDM)
-	api.Get("/messages/:channelId", JWTMiddleware, handlers.GetMessageFromChannel)
+	api.Post("/new_message/:serverId/:channelId", MessagesWriteMiddleware, handlers.NewMessage)
+	api.Post("/new_dm/:channelId", MessagesWriteMiddleware, handlers.NewDM)
+	api.Get("/messages/:channelId", MessagesReadMiddleware, handlers.GetMessageFromChannel)
+	api.Post("/ack/:channelId/:messageId", JWTMiddleware, handlers.AckMessage)
+	api.Get("/pins/:channelId", MessagesReadMiddleware, handlers.GetPinnedMessages)
+	api.Get("/scheduled", JWTMiddleware, handlers.GetScheduledItems)
+	api.Post("/scheduled/message", JWTMiddleware, handlers.ScheduleMessage)
+	api.Post("/scheduled/reminder", JWTMiddleware, handlers.CreateReminder)
+	api.Post("/scheduled/:itemId/cancel", JWTMiddleware, handlers.CancelScheduledItem)
+	api.Post("/pins/:channelId/:messageId", JWTMiddleware, handlers.PinMessage)
+	api.Post("/pins/:channelId/:messageId/unpin", JWTMiddleware, handlers.UnpinMessage)
+	api.Get("/notification_settings", JWTMiddleware, handlers.GetNotificationSettings)
+	api.Post("/notification_settings", JWTMiddleware, handlers.UpdateNotificationSettings)
+	api.Post("/notification_settings/reset/:targetId", JWTMiddleware, handlers.ResetNotificationSettings)
+	api.Post("/open_dm", JWTMiddleware, handlers.OpenDM)
+	api.Get("/dms", JWTMiddleware, handlers.GetDMs)
+	api.Post("/group_dm", JWTMiddleware, handlers.CreateGroupDM)
+	api.Post("/group_dm/:channelId/update", JWTMiddleware, handlers.UpdateGroupDM)
+	api.Post("/group_dm/:channelId/add", JWTMiddleware, handlers.AddGroupDMParticipant)
+	api.Post("/group_dm/:channelId/remove", JWTMiddleware, handlers.RemoveGroupDMParticipant)
+	api.Post("/group_dm/:channelId/leave", JWTMiddleware, handlers.LeaveGroupDM)
+	api.Post("/attachments/:channelId", MessagesWriteMiddleware, handlers.NewAttachment)
+	api.Post("/attachments/:attachmentId/complete", MessagesWriteMiddleware, handlers.CompleteAttachment)
 	api.Get("/new_signed_url_s3/:entity/:bucketName/:folder/:media/:version", JWTMiddleware, handlers.PutObjectInS3Bucket)
 	api.Get("/update/:media/:version", JWTMiddleware, handlers.UpdateMediaForUser)
+	api.Post("/server_media/:serverId/:media", JWTMiddleware, handlers.PresignServerMedia)
+	api.Post("/server_media/:serverId/:media/:version", JWTMiddleware, handlers.UpdateMediaForServer)
 	api.Post("/update_server_state", JWTMiddleware, handlers.UpdateServerState)
 	api.Get("/get_last_servers_state", JWTMiddleware, handlers.GetServerState)
 	api.Post("/create_server", JWTMiddleware, handlers.CreateServer)
 	api.Post("/delete_server", JWTMiddleware, handlers.DeleteServer)
-	api.Post("/leave_server", JWTMiddleware, handlers.LeaveServer)
-	api.Post("/join_server", JWTMiddleware, handlers.JoinServer)
+	api.Post("/leave_server", ServersJoinMiddleware, handlers.LeaveServer)
+	api.Post("/join_server", ServersJoinMiddleware, handlers.JoinServer)
 	api.Post("/create_channel", JWTMiddleware, handlers.CreateChannel)
 	api.Post("/delete_channel", JWTMiddleware, handlers.DeleteChannel)
+	api.Get("/audit_logs/:serverId", JWTMiddleware, handlers.GetAuditLogs)
+	api.Get("/discovery", JWTMiddleware, handlers.DiscoverServers)
+	api.Post("/discovery/:serverId/settings", JWTMiddleware, handlers.UpdateServerDiscovery)
+	api.Post("/discovery/:serverId/join", JWTMiddleware, handlers.JoinPublicServer)
+	api.Get("/bots", JWTMiddleware, handlers.GetBots)
+	api.Post("/bots", JWTMiddleware, handlers.CreateBot)
+	api.Post("/bots/:botId/update", JWTMiddleware, handlers.UpdateBot)
+	api.Post("/bots/:botId/delete", JWTMiddleware, handlers.DeleteBot)
+	api.Get("/bots/:botId/tokens", JWTMiddleware, handlers.GetBotTokens)
+	api.Post("/bots/:botId/tokens", JWTMiddleware, handlers.CreateBotToken)
+	api.Post("/bots/:botId/tokens/:tokenId/revoke", JWTMiddleware, handlers.RevokeBotToken)
 	//
 	// User
 	user := api.Group("/user")
-	user.Post("/", handlers.CreateUser)
-	user.Post("/login", handlers.Login)
+	user.Post("/", middleware.IPRateLimit(ratelimit.FromEnv("RATE_LIMIT_SIGNUP", ratelimit.Limit{Burst: 3, Per: time.Hour})), handlers.CreateUser)
+	user.Post("/login", middleware.IPRateLimit(ratelimit.FromEnv("RATE_LIMIT_LOGIN", ratelimit.Limit{Burst: 5, Per: time.Minute})), handlers.Login)
 	user.Get("/servers", JWTMiddleware, handlers.GetServersOfUser)
+	user.Post("/login/2fa", middleware.IPRateLimit(ratelimit.FromEnv("RATE_LIMIT_LOGIN", ratelimit.Limit{Burst: 5, Per: time.Minute})), handlers.LoginTwoFactor)
+	user.Get("/oidc", handlers.GetOIDCProviders)
+	user.Get("/oidc/:provider/authorize", middleware.IPRateLimit(ratelimit.FromEnv("RATE_LIMIT_LOGIN", ratelimit.Limit{Burst: 5, Per: time.Minute})), handlers.OIDCAuthorize)
+	user.Get("/oidc/:provider/callback", handlers.OIDCCallback)
+	user.Post("/refresh", middleware.IPRateLimit(ratelimit.FromEnv("RATE_LIMIT_REFRESH", ratelimit.Limit{Burst: 10, Per: time.Minute})), handlers.RefreshSession)
+	user.Post("/logout", JWTMiddleware, handlers.Logout)
+	user.Post("/verify_email", handlers.VerifyEmail)
+	user.Post("/verify_email/resend", JWTMiddleware, handlers.ResendVerificationEmail)
+	user.Post("/password_reset", middleware.IPRateLimit(ratelimit.FromEnv("RATE_LIMIT_PASSWORD_RESET", ratelimit.Limit{Burst: 3, Per: time.Hour})), handlers.RequestPasswordReset)
+	user.Post("/password_reset/confirm", middleware.IPRateLimit(ratelimit.FromEnv("RATE_LIMIT_LOGIN", ratelimit.Limit{Burst: 5, Per: time.Minute})), handlers.ConfirmPasswordReset)
+	user.Post("/settings", JWTMiddleware, handlers.UpdateAccount)
+	user.Post("/delete", JWTMiddleware, handlers.DeleteAccount)
+	user.Get("/export", JWTMiddleware, handlers.GetDataExports)
+	user.Post("/export", JWTMiddleware, handlers.RequestDataExport)
+	user.Get("/sessions", JWTMiddleware, handlers.GetSessions)
+	user.Post("/sessions/revoke_others", JWTMiddleware, handlers.RevokeOtherSessions)
+	user.Post("/sessions/:sessionId/revoke", JWTMiddleware, handlers.RevokeSession)
+	user.Post("/2fa/setup", JWTMiddleware, handlers.SetupTwoFactor)
+	user.Post("/2fa/enable", JWTMiddleware, handlers.EnableTwoFactor)
+	user.Post("/2fa/disable", JWTMiddleware, handlers.DisableTwoFactor)
+	user.Post("/2fa/recovery_codes", JWTMiddleware, handlers.RegenerateRecoveryCodes)
 }
@@ -0,0 +1,79 @@
+// Package storage hides the object storage used for medias and attachments
+// behind a small interface, so the backend can run against S3, any S3
+// compatible server (MinIO) or the local disk.
+//
+// Objects under the "private/" prefix must only be reachable through a
+// presigned GET URL, everything else is publicly readable through URL.
+package storage
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModifiedAt  time.Time
+}
+
+type Storage interface {
+	// PresignPut returns a URL the client can PUT the object to.
+	PresignPut(key string, expires time.Duration) (string, error)
+	// PresignGet returns a time limited URL to download the object.
+	PresignGet(key string, expires time.Duration) (string, error)
+	// Get downloads the object, ErrNotFound when it doesn't exist. No more
+	// than maxBytes + 1 bytes are read, so a larger object can be told apart
+	// without being held in memory.
+	Get(key string, maxBytes int64) ([]byte, error)
+	Put(key string, data []byte, contentType string) error
+	Delete(key string) error
+	// Head returns ErrNotFound when the object doesn't exist.
+	Head(key string) (ObjectInfo, error)
+	// List returns the objects whose key starts with prefix, without their
+	// content type.
+	List(prefix string) ([]ObjectInfo, error)
+	// URL is the public URL of the object.
+	URL(key string) string
+}
+
+var Store Storage
+
+// InitStorage picks the backend from STORAGE_DRIVER ("s3" by default, or
+// "local").
+func InitStorage() {
+	switch env.Variable("STORAGE_DRIVER") {
+	case "local":
+		Store = NewLocal(LocalConfig{
+			Dir:     withDefault(env.Variable("STORAGE_LOCAL_DIR"), "./uploads"),
+			BaseURL: env.Variable("STORAGE_LOCAL_URL"),
+			Secret:  withDefault(env.Variable("STORAGE_SECRET"), env.Variable("SECRET")),
+		})
+	default:
+		Store = NewS3(S3Config{
+			Profile:   withDefault(env.Variable("S3_PROFILE"), "userbulles"),
+			Region:    env.Variable("S3_REGION"),
+			Endpoint:  env.Variable("S3_ENDPOINT"),
+			Bucket:    withDefault(env.Variable("S3_BUCKET"), "bulles-bucket"),
+			PublicURL: withDefault(env.Variable("S3_PUBLIC_URL"), env.Variable("CLOUDFRONT_URL")),
+		})
+	}
+}
+
+func withDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+func isPrivate(key string) bool {
+	return strings.HasPrefix(key, "private/")
+}
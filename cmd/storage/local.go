@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// localRoute is where the local driver serves and receives objects.
+const localRoute = "/storage"
+
+type LocalConfig struct {
+	// Dir is where the objects are written on disk.
+	Dir string
+	// BaseURL is the public URL of this backend, e.g. https://localhost.
+	BaseURL string
+	// Secret signs the presigned URLs.
+	Secret string
+}
+
+// Local stores objects on the disk and serves them through Fiber, which
+// makes it possible to run the backend without any AWS account. Presigned
+// URLs are HMAC signed and checked by the routes added with Register.
+type Local struct {
+	dir     string
+	baseURL string
+	secret  []byte
+}
+
+func NewLocal(cfg LocalConfig) *Local {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	return &Local{
+		dir:     cfg.Dir,
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		secret:  []byte(cfg.Secret),
+	}
+}
+
+// Register adds the upload and download routes of the local driver.
+func (store *Local) Register(app *fiber.App) {
+	app.Put(localRoute+"/*", store.handlePut)
+	app.Get(localRoute+"/*", store.handleGet)
+}
+
+func (store *Local) PresignPut(key string, expires time.Duration) (string, error) {
+	return store.presign(fiber.MethodPut, key, expires)
+}
+
+func (store *Local) PresignGet(key string, expires time.Duration) (string, error) {
+	return store.presign(fiber.MethodGet, key, expires)
+}
+
+func (store *Local) Get(key string, maxBytes int64) ([]byte, error) {
+	filePath, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, maxBytes+1))
+}
+
+// Put ignores the content type, it is guessed from the extension when the
+// object is served.
+func (store *Local) Put(key string, data []byte, contentType string) error {
+	filePath, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, data, 0o644)
+}
+
+func (store *Local) Delete(key string) error {
+	filePath, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (store *Local) Head(key string) (ObjectInfo, error) {
+	filePath, err := store.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	stat, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ObjectInfo{}, ErrNotFound
+	} else if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{Key: key, Size: stat.Size(), ContentType: mime.TypeByExtension(path.Ext(key)), ModifiedAt: stat.ModTime()}, nil
+}
+
+// List walks the directory the prefix is in, the objects being files named
+// after their keys.
+func (store *Local) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+
+	root := store.dir
+	if dir := path.Dir(prefix); dir != "." {
+		dirPath, err := store.path(dir)
+		if err != nil {
+			return nil, err
+		}
+		root = dirPath
+	}
+
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		relative, err := filepath.Rel(store.dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(relative)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), ModifiedAt: info.ModTime()})
+		return nil
+	})
+
+	return objects, err
+}
+
+func (store *Local) URL(key string) string {
+	return store.baseURL + localRoute + "/" + key
+}
+
+func (store *Local) presign(method string, key string, expires time.Duration) (string, error) {
+	if _, err := store.path(key); err != nil {
+		return "", err
+	}
+
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	query.Set("signature", store.sign(method, key, expiresAt))
+
+	return store.URL(key) + "?" + query.Encode(), nil
+}
+
+func (store *Local) sign(method string, key string, expiresAt string) string {
+	mac := hmac.New(sha256.New, store.secret)
+	mac.Write([]byte(method + "\n" + key + "\n" + expiresAt))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (store *Local) verify(c *fiber.Ctx, key string) bool {
+	expiresAt := c.Query("expires")
+	expires, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+
+	expected := store.sign(c.Method(), key, expiresAt)
+	return hmac.Equal([]byte(expected), []byte(c.Query("signature")))
+}
+
+// path maps a key to its file, refusing anything that would escape Dir.
+func (store *Local) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned != "/"+key {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+
+	return filepath.Join(store.dir, filepath.FromSlash(cleaned)), nil
+}
+
+func (store *Local) handlePut(c *fiber.Ctx) error {
+	key := c.Params("*")
+	if !store.verify(c, key) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid signature"})
+	}
+
+	if _, err := store.path(key); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid key"})
+	}
+
+	if err := store.Put(key, c.Body(), c.Get(fiber.HeaderContentType)); err != nil {
+		log.Error(err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (store *Local) handleGet(c *fiber.Ctx) error {
+	key := c.Params("*")
+	if (isPrivate(key) || c.Query("signature") != "") && !store.verify(c, key) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid signature"})
+	}
+
+	filePath, err := store.path(key)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid key"})
+	}
+
+	if _, err := os.Stat(filePath); err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	return c.SendFile(filePath)
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type S3Config struct {
+	Profile string
+	Region  string
+	// Endpoint points the client to an S3 compatible server such as MinIO,
+	// requests are then made path style.
+	Endpoint  string
+	Bucket    string
+	PublicURL string
+}
+
+type S3 struct {
+	client    *s3.Client
+	presigner *s3.PresignClient
+	bucket    string
+	publicURL string
+}
+
+func NewS3(cfg S3Config) *S3 {
+	var options []func(*config.LoadOptions) error
+	if cfg.Profile != "" {
+		options = append(options, config.WithSharedConfigProfile(cfg.Profile))
+	}
+	if cfg.Region != "" {
+		options = append(options, config.WithRegion(cfg.Region))
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(context.TODO(), options...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	return &S3{
+		client:    client,
+		presigner: s3.NewPresignClient(client),
+		bucket:    cfg.Bucket,
+		publicURL: cfg.PublicURL,
+	}
+}
+
+func (store *S3) PresignPut(key string, expires time.Duration) (string, error) {
+	request, err := store.presigner.PresignPutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		log.Errorf("Couldn't get a presigned request to put %v:%v. Here's why: %v\n", store.bucket, key, err)
+		return "", err
+	}
+
+	return request.URL, nil
+}
+
+func (store *S3) PresignGet(key string, expires time.Duration) (string, error) {
+	request, err := store.presigner.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		log.Errorf("Couldn't get a presigned request to get %v:%v. Here's why: %v\n", store.bucket, key, err)
+		return "", err
+	}
+
+	return request.URL, nil
+}
+
+func (store *S3) Get(key string, maxBytes int64) ([]byte, error) {
+	object, err := store.client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer object.Body.Close()
+
+	return io.ReadAll(io.LimitReader(object.Body, maxBytes+1))
+}
+
+func (store *S3) Put(key string, data []byte, contentType string) error {
+	_, err := store.client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String(store.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+
+	return err
+}
+
+func (store *S3) Delete(key string) error {
+	_, err := store.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	})
+
+	return err
+}
+
+func (store *S3) Head(key string) (ObjectInfo, error) {
+	head, err := store.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return ObjectInfo{}, ErrNotFound
+		}
+		return ObjectInfo{}, err
+	}
+
+	info := ObjectInfo{Key: key, Size: head.ContentLength}
+	if head.ContentType != nil {
+		info.ContentType = *head.ContentType
+	}
+	if head.LastModified != nil {
+		info.ModifiedAt = *head.LastModified
+	}
+
+	return info, nil
+}
+
+func (store *S3) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+
+	paginator := s3.NewListObjectsV2Paginator(store.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(store.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			info := ObjectInfo{Key: aws.ToString(object.Key), Size: object.Size}
+			if object.LastModified != nil {
+				info.ModifiedAt = *object.LastModified
+			}
+			objects = append(objects, info)
+		}
+	}
+
+	return objects, nil
+}
+
+func (store *S3) URL(key string) string {
+	return strings.TrimSuffix(store.publicURL, "/") + "/" + key
+}
+
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && (apiErr.ErrorCode() == "NotFound" || apiErr.ErrorCode() == "NoSuchKey")
+}
@@ -0,0 +1,324 @@
+// Package unfurl fetches the OpenGraph and oEmbed metadata of the links
+// posted in messages.
+//
+// Every connection is checked once the address has been resolved, so links
+// (or redirects, or DNS answers) pointing to loopback, private or link-local
+// ranges are refused unless AllowPrivate is set, which is only meant for
+// running against a local HTTP stand-in.
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrForbiddenAddress = errors.New("address not allowed")
+
+type Config struct {
+	Timeout time.Duration
+	// MaxBytes is the most read from a page or an oEmbed document.
+	MaxBytes     int64
+	MaxRedirects int
+	AllowPrivate bool
+	UserAgent    string
+}
+
+type Embed struct {
+	Url         string `json:"url"`
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	SiteName    string `json:"siteName"`
+	Image       string `json:"image"`
+}
+
+type Unfurler struct {
+	client *http.Client
+	config Config
+}
+
+func New(config Config) *Unfurler {
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.MaxBytes == 0 {
+		config.MaxBytes = 1 << 20
+	}
+	if config.MaxRedirects == 0 {
+		config.MaxRedirects = 3
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = "BullesBot/1.0 (link preview)"
+	}
+
+	dialer := &net.Dialer{
+		Timeout: config.Timeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			if config.AllowPrivate {
+				return nil
+			}
+
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return ErrForbiddenAddress
+			}
+
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   config.Timeout,
+		ResponseHeaderTimeout: config.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Unfurler{
+		config: config,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > config.MaxRedirects {
+					return fmt.Errorf("too many redirects")
+				}
+				return checkScheme(req.URL)
+			},
+		},
+	}
+}
+
+// Unfurl returns the embed of the page at rawURL, preferring its oEmbed
+// document when the page advertises one.
+func (unfurler *Unfurler) Unfurl(ctx context.Context, rawURL string) (Embed, error) {
+	pageURL, err := url.Parse(rawURL)
+	if err != nil {
+		return Embed{}, err
+	}
+
+	if err := checkScheme(pageURL); err != nil {
+		return Embed{}, err
+	}
+
+	body, contentType, finalURL, err := unfurler.get(ctx, pageURL.String())
+	if err != nil {
+		return Embed{}, err
+	}
+
+	embed := Embed{Url: rawURL, Type: "link"}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(mediaType, "image/") {
+		embed.Type = "image"
+		embed.Image = finalURL.String()
+		return embed, nil
+	}
+
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Embed{}, fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	page := parseHTML(string(body))
+	embed.Title = firstOf(page.meta["og:title"], page.meta["twitter:title"], page.title)
+	embed.Description = firstOf(page.meta["og:description"], page.meta["twitter:description"], page.meta["description"])
+	embed.SiteName = firstOf(page.meta["og:site_name"], finalURL.Hostname())
+	embed.Image = resolve(finalURL, firstOf(page.meta["og:image"], page.meta["twitter:image"]))
+	if ogType := page.meta["og:type"]; ogType != "" {
+		embed.Type = ogType
+	}
+
+	if page.oembed != "" {
+		if oembed, err := unfurler.oembed(ctx, resolve(finalURL, page.oembed)); err == nil {
+			embed.Title = firstOf(oembed.Title, embed.Title)
+			embed.SiteName = firstOf(oembed.ProviderName, embed.SiteName)
+			embed.Image = firstOf(oembed.ThumbnailURL, embed.Image)
+			embed.Type = firstOf(oembed.Type, embed.Type)
+		}
+	}
+
+	if embed.Title == "" && embed.Description == "" && embed.Image == "" {
+		return Embed{}, fmt.Errorf("nothing to show for %s", rawURL)
+	}
+
+	return embed, nil
+}
+
+type oembedDocument struct {
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+func (unfurler *Unfurler) oembed(ctx context.Context, rawURL string) (oembedDocument, error) {
+	var document oembedDocument
+
+	oembedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return document, err
+	}
+
+	if err := checkScheme(oembedURL); err != nil {
+		return document, err
+	}
+
+	body, _, _, err := unfurler.get(ctx, oembedURL.String())
+	if err != nil {
+		return document, err
+	}
+
+	err = json.Unmarshal(body, &document)
+	return document, err
+}
+
+func (unfurler *Unfurler) get(ctx context.Context, rawURL string) ([]byte, string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	req.Header.Set("User-Agent", unfurler.config.UserAgent)
+	req.Header.Set("Accept", "text/html, application/json;q=0.9, image/*;q=0.8")
+
+	res, err := unfurler.client.Do(req)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, unfurler.config.MaxBytes))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return body, res.Header.Get("Content-Type"), res.Request.URL, nil
+}
+
+var (
+	metaTagRegex   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	linkTagRegex   = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	titleTagRegex  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	attributeRegex = regexp.MustCompile(`(?is)([a-z:-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+)
+
+type page struct {
+	title  string
+	meta   map[string]string
+	oembed string
+}
+
+// parseHTML only looks at the tags that matter for an embed, which is far
+// more forgiving than a full parser with the pages found in the wild.
+func parseHTML(body string) page {
+	result := page{meta: make(map[string]string)}
+
+	if match := titleTagRegex.FindStringSubmatch(body); match != nil {
+		result.title = clean(match[1])
+	}
+
+	for _, tag := range metaTagRegex.FindAllString(body, -1) {
+		attributes := parseAttributes(tag)
+		key := strings.ToLower(firstOf(attributes["property"], attributes["name"]))
+		if key != "" && result.meta[key] == "" {
+			result.meta[key] = clean(attributes["content"])
+		}
+	}
+
+	for _, tag := range linkTagRegex.FindAllString(body, -1) {
+		attributes := parseAttributes(tag)
+		if strings.EqualFold(attributes["rel"], "alternate") && strings.EqualFold(attributes["type"], "application/json+oembed") {
+			result.oembed = html.UnescapeString(attributes["href"])
+			break
+		}
+	}
+
+	return result
+}
+
+func parseAttributes(tag string) map[string]string {
+	attributes := make(map[string]string)
+	for _, match := range attributeRegex.FindAllStringSubmatch(tag, -1) {
+		attributes[strings.ToLower(match[1])] = strings.Trim(match[2], `"'`)
+	}
+
+	return attributes
+}
+
+// maxFieldBytes is how long a field of an embed can be.
+const maxFieldBytes = 500
+
+// clean collapses the whitespace of a field and cuts it to maxFieldBytes, on
+// a rune boundary as the embeds are stored and sent as UTF-8 text. Pages in
+// another encoding lose the bytes that aren't valid UTF-8.
+func clean(value string) string {
+	value = strings.ToValidUTF8(html.UnescapeString(value), "")
+	value = strings.Join(strings.Fields(value), " ")
+	if len(value) > maxFieldBytes {
+		end := maxFieldBytes
+		for end > 0 && !utf8.RuneStart(value[end]) {
+			end--
+		}
+		value = value[:end]
+	}
+
+	return value
+}
+
+func resolve(base *url.URL, reference string) string {
+	if reference == "" {
+		return ""
+	}
+
+	resolved, err := base.Parse(reference)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+		return ""
+	}
+
+	return resolved.String()
+}
+
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func checkScheme(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", target.Scheme)
+	}
+
+	return nil
+}
+
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || carrierGradeNAT.Contains(ip))
+}
@@ -0,0 +1,213 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// newPage serves body as an HTML page at / and the handlers given for the
+// other paths.
+func newPage(t *testing.T, body string, handlers map[string]http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, body)
+	})
+	for path, handler := range handlers {
+		mux.HandleFunc(path, handler)
+	}
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestUnfurlOpenGraph(t *testing.T) {
+	server := newPage(t, `<html><head>
+		<title>Fallback title</title>
+		<meta property="og:title" content="Bulles &amp; friends">
+		<meta property="og:description" content="  A place
+			to talk ">
+		<meta property="og:image" content="/cover.png">
+		<meta property="og:site_name" content="Bulles">
+	</head><body></body></html>`, nil)
+
+	embed, err := New(Config{AllowPrivate: true}).Unfurl(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Embed{
+		Url:         server.URL,
+		Type:        "link",
+		Title:       "Bulles & friends",
+		Description: "A place to talk",
+		SiteName:    "Bulles",
+		Image:       server.URL + "/cover.png",
+	}
+	if embed != want {
+		t.Fatalf("got %+v, want %+v", embed, want)
+	}
+}
+
+func TestUnfurlOEmbed(t *testing.T) {
+	server := newPage(t, `<html><head>
+		<title>Page title</title>
+		<link rel="alternate" type="application/json+oembed" href="/oembed?url=page&amp;format=json">
+	</head></html>`, map[string]http.HandlerFunc{
+		"/oembed": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("format") != "json" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"type": "video", "title": "A video", "provider_name": "Tube", "thumbnail_url": "https://example.com/thumb.jpg"}`)
+		},
+	})
+
+	embed, err := New(Config{AllowPrivate: true}).Unfurl(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if embed.Type != "video" || embed.Title != "A video" || embed.SiteName != "Tube" || embed.Image != "https://example.com/thumb.jpg" {
+		t.Fatalf("the oEmbed document wasn't used: %+v", embed)
+	}
+}
+
+func TestUnfurlImage(t *testing.T) {
+	server := newPage(t, "", map[string]http.HandlerFunc{
+		"/image.png": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+		},
+	})
+
+	embed, err := New(Config{AllowPrivate: true}).Unfurl(context.Background(), server.URL+"/image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if embed.Type != "image" || embed.Image != server.URL+"/image.png" {
+		t.Fatalf("got %+v", embed)
+	}
+}
+
+func TestUnfurlSizeLimit(t *testing.T) {
+	padding := strings.Repeat("<p>filler</p>", 1000)
+	server := newPage(t, `<html><head><title>`+padding+`</title></head><body>`+padding+`<meta property="og:title" content="Too far"></body></html>`, nil)
+
+	// Past the limit, neither the end of the title nor the tag are read.
+	if embed, err := New(Config{AllowPrivate: true, MaxBytes: 1024}).Unfurl(context.Background(), server.URL); err == nil {
+		t.Fatalf("read past the size limit: %+v", embed)
+	}
+
+	embed, err := New(Config{AllowPrivate: true}).Unfurl(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if embed.Title != "Too far" {
+		t.Fatalf("got %+v", embed)
+	}
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "whitespace", value: "  a\n\tb  ", want: "a b"},
+		{name: "entities", value: "Tom &amp; Jerry", want: "Tom & Jerry"},
+		{name: "cut on a rune boundary", value: strings.Repeat("a", maxFieldBytes-1) + "é", want: strings.Repeat("a", maxFieldBytes-1)},
+		{name: "cut between runes", value: strings.Repeat("é", maxFieldBytes), want: strings.Repeat("é", maxFieldBytes/2)},
+		{name: "invalid UTF-8", value: "caf\xe9", want: "caf"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := clean(test.value)
+			if got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+
+			if !utf8.ValidString(got) || len(got) > maxFieldBytes {
+				t.Fatalf("%q isn't a valid field", got)
+			}
+		})
+	}
+}
+
+func TestUnfurlTimeout(t *testing.T) {
+	server := newPage(t, "", map[string]http.HandlerFunc{
+		"/slow": func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		},
+	})
+
+	start := time.Now()
+	if _, err := New(Config{AllowPrivate: true, Timeout: 100 * time.Millisecond}).Unfurl(context.Background(), server.URL+"/slow"); err == nil {
+		t.Fatal("a page slower than the timeout was unfurled")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("gave up after %s", elapsed)
+	}
+}
+
+func TestUnfurlRefusesPrivateAddresses(t *testing.T) {
+	server := newPage(t, `<title>Internal</title>`, nil)
+
+	_, err := New(Config{}).Unfurl(context.Background(), server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("got %v, want ErrForbiddenAddress", err)
+	}
+}
+
+func TestUnfurlRefusesOtherSchemes(t *testing.T) {
+	for _, rawURL := range []string{"file:///etc/passwd", "ftp://example.com/", "gopher://example.com/"} {
+		if _, err := New(Config{}).Unfurl(context.Background(), rawURL); err == nil {
+			t.Fatalf("%s was unfurled", rawURL)
+		}
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.0.0.1":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+	}
+
+	for address, public := range tests {
+		if got := isPublic(net.ParseIP(address)); got != public {
+			t.Errorf("isPublic(%s) = %v, want %v", address, got, public)
+		}
+	}
+}
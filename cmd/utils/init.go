@@ -15,13 +15,12 @@ func GetServerInformations(serverId string) (models.Server, error) {
 	db := database.DB
 	var server models.Server
 
-	query := "select * from servers where server_id = ?"
-	if err := db.Query(query, serverId).Scan(&server.ServerId, &server.CreatedAt, &server.Banner, &server.Description, &server.Name, &server.Owner, &server.Status); err != nil {
-		log.Error(err)
+	query := "SELECT server_id, created_at, banner, banner_version, category, description, icon, icon_version, name, owner, status, tags FROM servers WHERE server_id = ?"
+	if err := db.Query(query, serverId).Scan(&server.ServerId, &server.CreatedAt, &server.Banner, &server.BannerVersion, &server.Category, &server.Description, &server.Icon, &server.IconVersion, &server.Name, &server.Owner, &server.Status, &server.Tags); err != nil {
+		return server, err
 	}
 
 	return server, nil
-
 }
 
 func GetChannelsFromServer(c *fiber.Ctx) []models.Channel {
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var linkRegex = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// ParseLinks returns up to max distinct http(s) links of a message content.
+// Links wrapped in <> are not embedded, like in markdown.
+func ParseLinks(content string, max int) []string {
+	var links []string
+	seen := make(map[string]bool)
+
+	for _, match := range linkRegex.FindAllStringIndex(content, -1) {
+		if match[0] > 0 && content[match[0]-1] == '<' {
+			continue
+		}
+
+		link := strings.TrimRight(content[match[0]:match[1]], ".,;:!?)]}")
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+
+		if len(links) == max {
+			break
+		}
+	}
+
+	return links
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"regexp"
+
+	"github.com/gocql/gocql"
+)
+
+var (
+	userMentionRegex     = regexp.MustCompile(`<@([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})>`)
+	roleMentionRegex     = regexp.MustCompile(`<@&([^<>\s]+)>`)
+	everyoneMentionRegex = regexp.MustCompile(`(^|[^\w<])@everyone\b`)
+)
+
+// ParseMentions extracts the <@user>, <@&role> and @everyone tokens of a
+// message content. Duplicates are only returned once.
+func ParseMentions(content string) ([]gocql.UUID, []string, bool) {
+	var users []gocql.UUID
+	var roles []string
+	seenUsers := make(map[gocql.UUID]bool)
+	seenRoles := make(map[string]bool)
+
+	for _, match := range userMentionRegex.FindAllStringSubmatch(content, -1) {
+		id, err := gocql.ParseUUID(match[1])
+		if err != nil || seenUsers[id] {
+			continue
+		}
+		seenUsers[id] = true
+		users = append(users, id)
+	}
+
+	for _, match := range roleMentionRegex.FindAllStringSubmatch(content, -1) {
+		if seenRoles[match[1]] {
+			continue
+		}
+		seenRoles[match[1]] = true
+		roles = append(roles, match[1])
+	}
+
+	return users, roles, everyoneMentionRegex.MatchString(content)
+}
@@ -3,20 +3,35 @@ package main
 import (
 	"net/http"
 
+	"github.com/Mind-thatsall/fiber-htmx/cmd/auth"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/database"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/env"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/handlers"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/mailer"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/oidc"
 	"github.com/Mind-thatsall/fiber-htmx/cmd/router"
+	"github.com/Mind-thatsall/fiber-htmx/cmd/storage"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
 func main() {
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		// Large enough for the attachments uploaded to the local storage.
+		BodyLimit: 32 << 20,
+	})
 
 	database.InitScyllaDB()
-	handlers.NewPresigner()
+	storage.InitStorage()
+	auth.InitAuth()
+	mailer.InitMailer()
+	oidc.InitProviders()
+	handlers.InitRateLimits()
+	handlers.StartUnfurlWorkers(4)
+	handlers.StartScheduler()
+	handlers.StartAccountDeletions()
+	handlers.StartExportSweeper()
 
 	app.Use("/ws", func(c *fiber.Ctx) error {
 		// IsWebSocketUpgrade returns true if the client